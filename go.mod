@@ -18,4 +18,5 @@ require (
 	k8s.io/client-go v0.21.4
 	k8s.io/klog/v2 v2.10.0
 	sigs.k8s.io/controller-runtime v0.9.7
+	sigs.k8s.io/yaml v1.2.0
 )
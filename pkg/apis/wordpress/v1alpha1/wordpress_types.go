@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -36,8 +37,63 @@ type RouteSpec struct {
 	// The path for the route. Defaults to /.
 	// +optional
 	Path string `json:"path"`
+	// Primary marks the route the site home and site URLs derive from. At
+	// most one route can be primary. Defaults to the first route.
+	// +optional
+	Primary bool `json:"primary,omitempty"`
+	// Annotations holds route specific metadata (eg. rate limiting settings)
+	// for the ingress integrations consuming the routes. They are not applied
+	// to the generated Ingress, which is shared by all routes; use
+	// ingressAnnotations for that.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
+// VolumePermissionsMode defines how the volumes ownership is set.
+type VolumePermissionsMode string
+
+const (
+	// VolumePermissionsChown chowns the volumes from a root init container.
+	VolumePermissionsChown VolumePermissionsMode = "Chown"
+
+	// VolumePermissionsFSGroup relies on the pod fsGroup.
+	VolumePermissionsFSGroup VolumePermissionsMode = "FSGroup"
+)
+
+// SecurityProfile defines a preset of container security context settings.
+type SecurityProfile string
+
+const (
+	// SecurityProfilePrivilegedLegacy keeps the historical security context,
+	// which only sets the user and group the containers run as.
+	SecurityProfilePrivilegedLegacy SecurityProfile = "privileged-legacy"
+
+	// SecurityProfileBaseline disallows privilege escalation and applies the
+	// runtime default seccomp profile.
+	SecurityProfileBaseline SecurityProfile = "baseline"
+
+	// SecurityProfileRestricted complies with the restricted Pod Security
+	// Standard.
+	SecurityProfileRestricted SecurityProfile = "restricted"
+)
+
+// InitResourceProfile defines a preset of init container resources.
+type InitResourceProfile string
+
+const (
+	// InitResourceProfileSmall requests 50m CPU and 64Mi memory, limited to
+	// 500m CPU and 256Mi memory.
+	InitResourceProfileSmall InitResourceProfile = "small"
+
+	// InitResourceProfileMedium requests 100m CPU and 128Mi memory, limited
+	// to 1 CPU and 512Mi memory. The git clone containers get twice the memory.
+	InitResourceProfileMedium InitResourceProfile = "medium"
+
+	// InitResourceProfileLarge requests 250m CPU and 256Mi memory, limited to
+	// 2 CPUs and 1Gi memory. The git clone containers get twice the memory.
+	InitResourceProfileLarge InitResourceProfile = "large"
+)
+
 // WordpressConditionType defines condition types of a backup resources.
 type WordpressConditionType string
 
@@ -66,14 +122,56 @@ const (
 
 	// WPCronTriggeringReason is the reason for successfully triggering wp-cron.
 	WPCronTriggeringReason = "WPCronTriggering"
+
+	// WPCronSuspendedReason is the reason for not triggering a suspended wp-cron.
+	WPCronSuspendedReason = "WPCronSuspended"
+
+	// MediaVolumeValidCondition signals whether the media volume sources are valid.
+	// It is only added once the validation fails.
+	MediaVolumeValidCondition WordpressConditionType = "MediaVolumeValid"
+
+	// MediaVolumeValidReason is the reason for accepting the media volume sources.
+	MediaVolumeValidReason = "MediaVolumeValid"
+
+	// MediaVolumeInvalidReason is the reason for rejecting the media volume
+	// spec (eg. conflicting sources or an invalid path prefix).
+	MediaVolumeInvalidReason = "MediaVolumeInvalid"
 )
 
 // WordpressSpec defines the desired state of Wordpress.
 type WordpressSpec struct {
 	// Number of desired web pods. This is a pointer to distinguish between
-	// explicit zero and not specified. Defaults to 1.
+	// explicit zero and not specified. Defaults to 1. Values below the
+	// operator's --min-replicas floor (1 by default) are raised to it, unless
+	// the site is paused.
 	// +optional
 	Replicas *int32 `json:"replicas,omitempty"`
+	// ReplicasFromAnnotation is the key of a Wordpress object annotation
+	// holding the number of desired web pods, which then takes precedence over
+	// Replicas. It lets an external scaler drive the replicas count without
+	// changing the spec. Replicas is used while the annotation is missing, and
+	// values other than positive integers fail the reconciliation.
+	// +optional
+	ReplicasFromAnnotation string `json:"replicasFromAnnotation,omitempty"`
+	// Paused stops the operator from updating the objects managed for this
+	// site, without deleting them. The web deployment is paused as well, and
+	// only its replicas count is kept in sync. The status may go stale while
+	// the site is paused.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+	// MaintenanceWindow is a daily time window during which the web
+	// Deployment is paused, so spec or image changes don't get rolled out.
+	// The changes are still applied to the Deployment and roll out once the
+	// window ends. A Deployment created during the window is not paused.
+	// It has no effect in StatefulSet mode.
+	// +optional
+	MaintenanceWindow *MaintenanceWindowSpec `json:"maintenanceWindow,omitempty"`
+	// StatefulSetMode runs the web pods in a StatefulSet instead of a
+	// Deployment. When media uses a persistentVolumeClaim, each replica gets
+	// its own claim from it, while job and canary pods mount the claim of
+	// the first replica, so the claim access modes must allow that.
+	// +optional
+	StatefulSetMode bool `json:"statefulSetMode,omitempty"`
 	// Domains for which this this site answers.
 	// The first item is set as the "main domain" (eg. WP_HOME and WP_SITEURL constants).
 	// Deprecated: use Routes instead. This field will be dropped in next release.
@@ -91,6 +189,26 @@ type WordpressSpec struct {
 	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
 	// +optional
 	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// InitImagePullPolicy is the pull policy of the prepare-volumes and git
+	// clone images. If not set, the Kubernetes default for the image
+	// reference is used (IfNotPresent, unless tagged latest).
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
+	// +optional
+	InitImagePullPolicy corev1.PullPolicy `json:"initImagePullPolicy,omitempty"`
+	// TerminationMessagePolicy is set on the wordpress and the operator
+	// managed init containers. Defaults to FallbackToLogsOnError, so the last
+	// log lines of failed containers surface in the pod status.
+	// +kubebuilder:validation:Enum=File;FallbackToLogsOnError
+	// +optional
+	TerminationMessagePolicy corev1.TerminationMessagePolicy `json:"terminationMessagePolicy,omitempty"`
+	// ContainerName is the name of the main container in web pods.
+	// Defaults to wordpress.
+	// +optional
+	ContainerName string `json:"containerName,omitempty"`
+	// CLIContainerName is the name of the main container in wp-cli job pods.
+	// Defaults to wp-cli.
+	// +optional
+	CLIContainerName string `json:"cliContainerName,omitempty"`
 	// ImagePullSecrets defines additional secrets to use when pulling images
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
 	// ServiceAccountName is the name of the ServiceAccount to use to run this
@@ -98,11 +216,51 @@ type WordpressSpec struct {
 	// More info: https://kubernetes.io/docs/tasks/configure-pod-container/configure-service-account/
 	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// JobServiceAccountName is the name of the ServiceAccount to use to run
+	// this site's wp-cli job pods. Defaults to ServiceAccountName.
+	// +optional
+	JobServiceAccountName string `json:"jobServiceAccountName,omitempty"`
+	// JobMediaReadWrite mounts the media volume read-write in wp-cli job
+	// pods, even if media.readOnly is set (eg. for wp media regenerate).
+	// Volume sources set as read-only are still mounted read-only.
+	// +optional
+	JobMediaReadWrite bool `json:"jobMediaReadWrite,omitempty"`
+	// CaptureJobOutput tees the output of the wp-cli job commands to
+	// /var/run/job-output/output.log, on a volume shared with the sidecars,
+	// so it can be collected (eg. into a ConfigMap) once the command ends.
+	// +optional
+	CaptureJobOutput bool `json:"captureJobOutput,omitempty"`
+	// JobProbe is set as the readiness probe of the wp-cli job container, so
+	// long running jobs can report their progress (eg. an exec probe checking
+	// a file the command touches periodically). It is not used as a liveness
+	// probe, since a failing check would restart the job command.
+	// By default, the wp-cli job container has no probes.
+	// +optional
+	JobProbe *corev1.Probe `json:"jobProbe,omitempty"`
+	// ManageServiceAccount makes the operator create or patch the service
+	// account named by ServiceAccountName, adding ImagePullSecrets to it
+	// instead of setting them on every pod. Secrets are only ever added to
+	// the service account, never removed from it.
+	// +optional
+	ManageServiceAccount bool `json:"manageServiceAccount,omitempty"`
 	// TLSSecretRef a secret containing the TLS certificates for this site.
 	// +optional
 	TLSSecretRef SecretRef `json:"tlsSecretRef,omitempty"`
 	// DeploymentStrategy allows setting the deployment strategy for the WordPress site
 	DeploymentStrategy *appsv1.DeploymentStrategy `json:"deploymentStrategy,omitempty"`
+	// ProgressDeadlineSeconds is the maximum time in seconds for the web
+	// deployment to make progress before it is considered to be failed.
+	// Defaults to 600.
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+	// VPA specifies a VerticalPodAutoscaler for the web deployment. It's only
+	// created if the VerticalPodAutoscaler CRD is installed in the cluster.
+	// +optional
+	VPA *VPASpec `json:"vpa,omitempty"`
+	// Canary specifies a canary deployment running a different image
+	// alongside the main web deployment.
+	// +optional
+	Canary *CanarySpec `json:"canary,omitempty"`
 	// CodeVolumeSpec specifies how the site's code gets mounted into the
 	// container. If not specified, a code volume won't get mounted at all.
 	// +optional
@@ -125,9 +283,106 @@ type WordpressSpec struct {
 	// If not specified, a default probe that makes a HTTP request on the "/-/php-ping" path will be used.
 	// +optional
 	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+	// DisableLivenessProbe removes the liveness probe of the wordpress
+	// container, so failing pods don't get restarted (eg. during an incident
+	// where restarts make things worse). The readiness probe is kept.
+	// +optional
+	DisableLivenessProbe bool `json:"disableLivenessProbe,omitempty"`
+	// StartupProbe allows setting a custom startup probe for the wordpress
+	// container. The liveness probe only runs after it succeeds, so slow
+	// starting sites should raise its FailureThreshold instead of the liveness
+	// thresholds. If not specified, a default probe that makes a HTTP request
+	// on the "/-/php-ping" path every 10 seconds and allows 30 failures is used,
+	// giving the container 5 minutes to start. The init containers (eg. git
+	// clone, install-wp) run before the wordpress container starts, so they
+	// don't count against this budget.
+	// +optional
+	StartupProbe *corev1.Probe `json:"startupProbe,omitempty"`
+	// ProbeScheme is the scheme used by the default readiness, liveness and
+	// startup probes. Defaults to HTTP.
+	// +kubebuilder:validation:Enum=HTTP;HTTPS
+	// +optional
+	ProbeScheme corev1.URIScheme `json:"probeScheme,omitempty"`
+	// ProbeHeaders are added to the requests of the default readiness and
+	// liveness probes (eg. X-Health-Check: true, for excluding them from
+	// traces). They override the Host header of the readiness probe, unless
+	// MultiRouteReadiness is set.
+	// +optional
+	ProbeHeaders []corev1.HTTPHeader `json:"probeHeaders,omitempty"`
+	// ProbeDefaults tunes the timing of the default readiness and liveness
+	// probes (eg. longer timeouts for CPU throttled pods), without setting
+	// the full probes. An invalid timing stops the site from being synced,
+	// and is reported by an InvalidProbeDefaults warning event.
+	// +optional
+	ProbeDefaults *ProbeDefaultsSpec `json:"probeDefaults,omitempty"`
+	// MultiRouteReadiness makes the default readiness probe request every
+	// route, with its own Host header and path, instead of just the main
+	// domain. The pod is ready only if all routes respond with a status code
+	// lower than 400. Every probe then runs one PHP request per route, so
+	// enabling it on sites with many routes increases the load on the pods,
+	// and all requests must complete within the probe timeout. Ignored if
+	// ReadinessProbe is set.
+	// +optional
+	MultiRouteReadiness bool `json:"multiRouteReadiness,omitempty"`
+	// MediaReadinessCheck makes the default readiness probe check the S3 or
+	// GCS media storage access first, by running a command in the wordpress
+	// container, with its env and so the media credentials. The pod is ready
+	// only if the command succeeds and the site responds with a status code
+	// lower than 400. The command runs on every probe, so it should be cheap
+	// and complete well within the probe timeout. Ignored if ReadinessProbe
+	// is set or the media is not offloaded to an object store.
+	// +optional
+	MediaReadinessCheck *MediaReadinessCheckSpec `json:"mediaReadinessCheck,omitempty"`
+	// DrainEndpoint is a local HTTP path of the wordpress container (eg.
+	// /-/drain) which gets called before stopping, so the web server stops
+	// accepting new requests.
+	// +kubebuilder:validation:Pattern=`^/[A-Za-z0-9/._~-]*$`
+	// +optional
+	DrainEndpoint string `json:"drainEndpoint,omitempty"`
+	// DrainSeconds is the time to wait for in-flight requests to finish,
+	// before stopping the wordpress container. It extends the pod termination
+	// grace period.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DrainSeconds int32 `json:"drainSeconds,omitempty"`
+	// PreStopBestEffort runs all the image pre-stop scripts, even if some of
+	// them fail, and stops them after 20 seconds, so a failing or hanging
+	// script doesn't delay the termination. By default, the scripts stop at
+	// the first failure and may run until the grace period ends.
+	// +optional
+	PreStopBestEffort bool `json:"preStopBestEffort,omitempty"`
+	// ShutdownOrder stops the sidecars (eg. an nginx proxy) before the
+	// wordpress container, so they stop accepting connections while php-fpm
+	// still serves the in-flight requests.
+	// +optional
+	ShutdownOrder *ShutdownOrderSpec `json:"shutdownOrder,omitempty"`
+	// Warmup requests local paths of the wordpress container after it starts,
+	// before the pod gets ready, warming up the opcache and the object cache.
+	// The paths are requested with ProbeScheme, without verifying the
+	// certificate over HTTPS.
+	// +optional
+	Warmup *WarmupSpec `json:"warmup,omitempty"`
+	// PostStartWarmupPath sets a native httpGet post-start hook, requesting
+	// this path of the wordpress container, with the main domain as Host.
+	// Kubernetes allows a single handler per hook, so it replaces the exec
+	// hook: the image post-start scripts are not run, and it can't be set
+	// together with Warmup. The
+	// kubelet requests the pod IP only once and kills the container if it
+	// can't connect, so the web server must listen as soon as the container
+	// starts; otherwise use Warmup.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	PostStartWarmupPath string `json:"postStartWarmupPath,omitempty"`
 	// WordpressBootstrapSpec specifies credentials used to install wordpress, on the first run.
 	// +optional
 	WordpressBootstrapSpec *WordpressBootstrapSpec `json:"bootstrap,omitempty"`
+	// Migrations specifies a command which is run on every rollout, before
+	// the web server starts. A failing migration blocks the web pods.
+	// +optional
+	Migrations *MigrationsSpec `json:"migrations,omitempty"`
+	// WPCron controls how wp-cron gets triggered for this site
+	// +optional
+	WPCron *WPCronSpec `json:"wpCron,omitempty"`
 	// WordpressPathPrefix is the path prefix under which wordpress is available.
 	// It defaults to /wp.
 	// +optional
@@ -136,11 +391,28 @@ type WordpressSpec struct {
 	// and cli pods.
 	// +optional
 	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
-	// Env defines environment variables which get passed into web and cli pods
+	// Files defines single files, taken from ConfigMap or Secret keys, which
+	// get mounted read-only into web and cli pods.
+	// +optional
+	Files []FileMount `json:"files,omitempty"`
+	// Env defines environment variables which get passed into web and cli pods.
+	// Values can reference WP_HOME, WP_SITEURL, WP_CORE_DIRECTORY,
+	// STACK_ROUTES, STACK_SITE_NAME and STACK_SITE_NAMESPACE, eg.
+	// $(STACK_SITE_NAMESPACE)-$(STACK_SITE_NAME), and, when enabled,
+	// CONTAINER_MEMORY_LIMIT, CONTAINER_CPU_LIMIT, GOMEMLIMIT,
+	// RUNTIME_MEMORY_LIMIT, PHP_SESSION_SAVE_PATH, WP_DEBUG, WP_DEBUG_LOG,
+	// WP_DEBUG_DISPLAY and VAULT_SECRETS_FILE. The media variables (eg.
+	// STACK_MEDIA_BUCKET or AWS_REGION) are set after them, so they can't be
+	// referenced.
 	// +optional
 	// +patchMergeKey=name
 	// +patchStrategy=merge
 	Env []corev1.EnvVar `json:"env,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	// Debug enables the WordPress debug mode, through the WP_DEBUG,
+	// WP_DEBUG_LOG and WP_DEBUG_DISPLAY env vars, which can still be
+	// overridden by Env.
+	// +optional
+	Debug *DebugSpec `json:"debug,omitempty"`
 	// EnvFrom defines envFrom's which get passed into web and cli containers
 	// +optional
 	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
@@ -148,27 +420,197 @@ type WordpressSpec struct {
 	// More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/
 	// +optional
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
-	// If specified, Pod node selector
+	// InitResourceProfile expands into the resources of the init containers
+	// which don't set any (eg. git, install-wp or the InitContainers).
+	// Defaults to no resources.
+	// +kubebuilder:validation:Enum=small;medium;large
+	// +optional
+	InitResourceProfile InitResourceProfile `json:"initResourceProfile,omitempty"`
+	// If specified, Pod node selector. It gets merged over the operator's
+	// --default-node-selector, its keys taking precedence.
 	// +optional
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// RestartOnSecretChange rolls out the web pods when the data of the
+	// secrets referenced in EnvFrom changes (eg. a rotated database
+	// password). The operator managed secret always triggers a rollout.
+	// +optional
+	RestartOnSecretChange bool `json:"restartOnSecretChange,omitempty"`
 	// If specified, the pod's tolerations.
 	// +optional
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// DefaultTolerationSeconds sets the tolerationSeconds of the
+	// node.kubernetes.io/not-ready and node.kubernetes.io/unreachable
+	// tolerations, otherwise added by the cluster with 300 seconds, so pods
+	// get rescheduled faster from failing nodes. Tolerations already set for
+	// these taints take precedence.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DefaultTolerationSeconds *int64 `json:"defaultTolerationSeconds,omitempty"`
 	// If specified, the pod's scheduling constraints
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// Architecture restricts the web and job pods to nodes of the given CPU
+	// architecture (eg. amd64), for images not built for every architecture
+	// in the cluster, by requiring the kubernetes.io/arch node label.
+	// +optional
+	Architecture string `json:"architecture,omitempty"`
 	// If specified, indicates the pod's priority class
 	// +optional
 	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// PodOverhead sets the resource overhead of the web and job pods, on top
+	// of the container requests and limits, for runtimes with a non-zero
+	// overhead, when it is not set from the RuntimeClass by the cluster.
+	// +optional
+	PodOverhead corev1.ResourceList `json:"podOverhead,omitempty"`
+	// EnableServiceLinks indicates whether information about services should
+	// be injected into pod's environment variables. If not specified, the
+	// cluster default is used.
+	// +optional
+	EnableServiceLinks *bool `json:"enableServiceLinks,omitempty"`
+	// ShareProcessNamespace enables sharing a single process namespace
+	// between all of the containers in the web pods (eg. for debugging sidecars).
+	// +optional
+	ShareProcessNamespace *bool `json:"shareProcessNamespace,omitempty"`
+	// RunAsGroup is the group ID the wordpress and init containers run as.
+	// Defaults to 33 (www-data).
+	// +optional
+	RunAsGroup *int64 `json:"runAsGroup,omitempty"`
+	// VolumePermissionsMode controls how the code, media and log volumes are
+	// made writable by the wordpress container. Chown (the default) chowns
+	// their root directories from a prepare-volumes init container running as
+	// root. FSGroup doesn't run any container as root, but sets the pod
+	// fsGroup to RunAsGroup instead, with the OnRootMismatch change policy.
+	// This way the kubelet recursively sets the group ownership, which is
+	// slower for big volumes the first time, and is not supported by every
+	// volume type (eg. hostPath or some NFS provisioners).
+	// +kubebuilder:validation:Enum=Chown;FSGroup
+	// +optional
+	VolumePermissionsMode VolumePermissionsMode `json:"volumePermissionsMode,omitempty"`
+	// SecurityProfile expands into the security context of the wordpress and
+	// init containers. The baseline profile disallows privilege escalation
+	// and uses the runtime default seccomp profile. The restricted profile
+	// also requires running as non-root and drops all capabilities, and
+	// defaults VolumePermissionsMode to FSGroup, so no container runs as
+	// root; it can't be combined with the Chown mode. Neither sets a read-only root filesystem. Defaults to
+	// privileged-legacy.
+	// +kubebuilder:validation:Enum=privileged-legacy;baseline;restricted
+	// +optional
+	SecurityProfile SecurityProfile `json:"securityProfile,omitempty"`
+	// SecurityContext overrides the fields it sets in the security context
+	// of the wordpress and init containers, which is otherwise expanded from
+	// SecurityProfile.
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
 	// IngressAnnotations for this Wordpress site
 	// +optional
 	IngressAnnotations map[string]string `json:"ingressAnnotations,omitempty"`
+	// ServiceAnnotations for this Wordpress site web Service (eg. to tune
+	// cloud load balancers). Annotations removed from here get removed from
+	// the Service.
+	// +optional
+	ServiceAnnotations map[string]string `json:"serviceAnnotations,omitempty"`
+	// DeploymentAnnotations are set on the web Deployment, or StatefulSet in
+	// StatefulSet mode (eg. argocd.argoproj.io/sync-wave). Annotations already
+	// set in the kubernetes.io domain (eg. the deployment revision) are not
+	// overwritten. Annotations removed from here get removed from the
+	// workload, except for the ones in these domains.
+	// +optional
+	DeploymentAnnotations map[string]string `json:"deploymentAnnotations,omitempty"`
 	// Additional init containers
 	// +optional
 	InitContainers []corev1.Container `json:"initContainers,omitempty"`
-	// Additional sidecar containers (eg. blackfire or tideways agent)
+	// PreVolumeInitContainers run first, before the prepare-volumes init
+	// container sets the volumes ownership (eg. to create the directory
+	// backing a hostPath volume).
+	// +optional
+	PreVolumeInitContainers []corev1.Container `json:"preVolumeInitContainers,omitempty"`
+	// Additional sidecar containers (eg. blackfire or tideways agent).
+	// Sidecars are passed through as they are, so they should define their
+	// own liveness and readiness probes. The operator managed sidecars (eg.
+	// the FPMExporter) come with default probes instead.
 	// +optional
 	Sidecars []corev1.Container `json:"sidecars,omitempty"`
+	// SharedSocketVolume mounts an emptyDir volume into the wordpress
+	// container and all sidecars, for sharing unix sockets between them (eg.
+	// php-fpm and nginx).
+	// +optional
+	SharedSocketVolume *SharedSocketVolumeSpec `json:"sharedSocketVolume,omitempty"`
+	// SharedSessionVolume mounts a volume shared by all the replicas (eg. a
+	// ReadWriteMany claim or NFS) at the PHP session path of the wordpress
+	// containers, for plugins keeping session state on disk.
+	// +optional
+	SharedSessionVolume *SharedSessionVolumeSpec `json:"sharedSessionVolume,omitempty"`
+	// VaultAgent runs a Vault agent init container rendering secrets into a
+	// memory backed volume, which gets mounted read-only into the wordpress
+	// containers.
+	// +optional
+	VaultAgent *VaultAgentSpec `json:"vaultAgent,omitempty"`
+	// FPMExporter runs a php-fpm prometheus exporter sidecar, for images
+	// which don't expose metrics themselves. It scrapes the php-fpm status
+	// over a socket on the shared socket volume, which gets enabled, and
+	// serves the metrics on the prometheus port instead of the wordpress
+	// container.
+	// +optional
+	FPMExporter *FPMExporterSpec `json:"fpmExporter,omitempty"`
+	// ExposePodInfo mounts a downward API volume exposing the pod labels and
+	// annotations at /etc/podinfo into the wordpress container.
+	// +optional
+	ExposePodInfo bool `json:"exposePodInfo,omitempty"`
+	// ExposeResourceLimits sets the CONTAINER_MEMORY_LIMIT (in bytes) and
+	// CONTAINER_CPU_LIMIT (in cores, rounded up) env vars from the container
+	// limits. If a limit is not set, the node allocatable value is used.
+	// +optional
+	ExposeResourceLimits bool `json:"exposeResourceLimits,omitempty"`
+	// ExposeMemoryLimitEnv sets the GOMEMLIMIT and RUNTIME_MEMORY_LIMIT env
+	// vars (in bytes) from the container memory limit, so the runtime can
+	// tune its garbage collection to it. They are not set if no memory limit
+	// is specified.
+	// +optional
+	ExposeMemoryLimitEnv bool `json:"exposeMemoryLimitEnv,omitempty"`
+	// LogVolumeMedium is the storage medium of the /var/log emptyDir volume.
+	// Set it to Memory to back the volume by tmpfs, in which case the written
+	// logs count against the container memory limit. Defaults to the node
+	// disk.
+	// +kubebuilder:validation:Enum=Memory
+	// +optional
+	LogVolumeMedium corev1.StorageMedium `json:"logVolumeMedium,omitempty"`
+	// DefaultVolumeSizeLimit bounds the emptyDir volumes used for code and
+	// media when no volume source is specified. Explicitly set volume
+	// sources are unaffected. Defaults to 10Gi.
+	// +optional
+	DefaultVolumeSizeLimit *resource.Quantity `json:"defaultVolumeSizeLimit,omitempty"`
+	// HardenSidecars applies a restrictive security context (runAsNonRoot, no
+	// privilege escalation) to sidecars which don't define one.
+	// +optional
+	HardenSidecars bool `json:"hardenSidecars,omitempty"`
+}
+
+// CanarySpec is the desired spec for the canary web deployment.
+type CanarySpec struct {
+	// Image is the WordPress runtime image used by the canary pods.
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+	// Number of desired canary pods. Defaults to 1.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Weight is the percentage of traffic intended for the canary track.
+	// It is informational only and gets set as an annotation on the canary
+	// deployment, for use by traffic splitting integrations.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	Weight int32 `json:"weight,omitempty"`
+}
+
+// VPASpec is the desired spec for the web deployment VerticalPodAutoscaler.
+type VPASpec struct {
+	// Enabled specifies if the VerticalPodAutoscaler should be created.
+	Enabled bool `json:"enabled,omitempty"`
+	// UpdateMode controls whether the autoscaler applies its recommendations.
+	// Defaults to Off, which only computes recommendations.
+	// +kubebuilder:validation:Enum=Off;Initial;Recreate;Auto
+	// +optional
+	UpdateMode string `json:"updateMode,omitempty"`
 }
 
 // GitVolumeSource is the desired spec for git code source.
@@ -176,20 +618,116 @@ type GitVolumeSource struct {
 	// Repository is the git repository for the code
 	Repository string `json:"repository"`
 	// GitRef to clone (can be a branch name, but it should point to a tag or a
-	// commit hash)
+	// commit hash). Defaults to the repository default branch.
 	// +optional
 	GitRef string `json:"reference,omitempty"`
+	// Detached checks out the GitRef as a detached HEAD instead of creating a
+	// local branch tracking it.
+	// +optional
+	Detached bool `json:"detached,omitempty"`
+	// AtomicClone clones the code into a temporary directory within the code
+	// volume, replacing the existing code only if the clone, the signature
+	// verification and the post-clone command succeed. Replacing the code is
+	// not atomic itself: the existing code is removed before the new one is
+	// moved in, so an interrupted replacement leaves the code incomplete until
+	// the next clone.
+	// +optional
+	AtomicClone bool `json:"atomicClone,omitempty"`
+	// SkipIfPopulated skips the clone when the code volume already holds a
+	// completed clone of the repository with GitRef checked out, so pods
+	// reusing a persistent code volume start faster. A clone is completed
+	// once the post-clone command succeeded. Branch references are not
+	// updated while the code is populated; set the
+	// wordpress.presslabs.org/force-reclone annotation to a new value to
+	// clone again.
+	// +optional
+	SkipIfPopulated bool `json:"skipIfPopulated,omitempty"`
+	// Mirror keeps a bare mirror of the repository in the .git-mirror
+	// directory of the code volume and checks out GitRef from it, always
+	// detached, as a worktree in WorktreeSubdir (defaults to worktree). The
+	// mirror holds the objects of all the repository refs, so it takes more
+	// space than a regular clone, but it is only fetched into, not cloned
+	// again, as long as the code volume is kept (eg. on clone retries).
+	// +optional
+	Mirror bool `json:"mirror,omitempty"`
+	// MountMedia mounts the media volume read-write in the git clone
+	// container, at the $MEDIA_DIR path, so a post-clone build step can write
+	// assets to it. The media volume must be set and not read-only.
+	// +optional
+	MountMedia bool `json:"mountMedia,omitempty"`
+	// WorktreeSubdir is the directory within the code volume where the
+	// repository is checked out (eg. current, for atomic deploy layouts).
+	// The code mounts of the wordpress container point within it as well.
+	// Defaults to the volume root.
+	// +kubebuilder:validation:Pattern=`^[^/]`
+	// +optional
+	WorktreeSubdir string `json:"worktreeSubdir,omitempty"`
+	// ReferenceRepoPath is the path, on the node, of a local mirror of the
+	// repository. When it holds a git repository, cloning borrows its objects
+	// (git clone --reference --dissociate), otherwise the code is cloned
+	// normally.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	ReferenceRepoPath string `json:"referenceRepoPath,omitempty"`
 	// Env defines env variables  which get passed to the git clone container
 	// +optional
 	// +patchMergeKey=name
 	// +patchStrategy=merge
 	Env []corev1.EnvVar `json:"env,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	// SSHKeySecret selects the secret key holding the SSH private key used
+	// for cloning. The key gets mounted as a file into the git clone
+	// container, instead of being passed as SSH_RSA_PRIVATE_KEY env.
+	// +optional
+	SSHKeySecret *corev1.SecretKeySelector `json:"sshKeySecret,omitempty"`
+	// CABundleSecret selects the secret key holding the PEM encoded CA
+	// bundle used to verify HTTPS repositories, set as GIT_SSL_CAINFO in the
+	// git clone container. Defaults to the system CAs.
+	// +optional
+	CABundleSecret *corev1.SecretKeySelector `json:"caBundleSecret,omitempty"`
+	// VerifySignature verifies the GPG signature of the checked out code
+	// (git verify-tag when GitRef is a tag, git verify-commit otherwise)
+	// before running the post-clone command, failing the git clone container
+	// if it is not signed by one of the given public keys. The git clone
+	// image must provide gpg.
+	// +optional
+	VerifySignature *GitSignatureVerification `json:"verifySignature,omitempty"`
 	// EnvFrom defines envFrom which get passed to the git clone container
 	// +optional
 	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+	// PostCloneCommand is run by the git clone container in the checked out
+	// code (eg. composer install), with the same env. The command must be
+	// available in the git clone image. A non-zero exit fails the container
+	// and, for atomic clones, keeps the existing code in place.
+	// +optional
+	PostCloneCommand []string `json:"postCloneCommand,omitempty"`
 	// EmptyDir volume to use for git cloning.
 	// +optional
 	EmptyDir *corev1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+	// SizeLimit bounds the EmptyDir volume the code is cloned into, taking
+	// precedence over the EmptyDir size limit.
+	// +optional
+	SizeLimit *resource.Quantity `json:"sizeLimit,omitempty"`
+}
+
+// ConfigGitVolumeSource is the desired spec for the git config source. It is
+// cloned into a separate emptyDir volume, by its own init container.
+type ConfigGitVolumeSource struct {
+	// Repository is the git repository for the config
+	// +kubebuilder:validation:MinLength=1
+	Repository string `json:"repository"`
+	// GitRef to clone (can be a branch name, but it should point to a tag or
+	// a commit hash). Defaults to the repository default branch.
+	// +optional
+	GitRef string `json:"reference,omitempty"`
+	// Env defines env variables which get passed to the config git clone
+	// container (eg. SSH_RSA_PRIVATE_KEY)
+	// +optional
+	// +patchMergeKey=name
+	// +patchStrategy=merge
+	Env []corev1.EnvVar `json:"env,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	// EnvFrom defines envFrom which get passed to the config git clone container
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
 }
 
 // S3VolumeSource is the desired spec for accessing media files over S3
@@ -198,10 +736,29 @@ type S3VolumeSource struct {
 	// Bucket for storing media files
 	// +kubebuilder:validation:MinLength=1
 	Bucket string `json:"bucket"`
-	// PathPrefix is the prefix for media files in bucket
+	// PathPrefix is the prefix for media files in bucket. It can reference
+	// the STACK_* and WP_* variables set by the operator, and the site Env
+	// variables, using $(VAR_NAME) expansion, eg. $(STACK_SITE_NAMESPACE)
 	PathPrefix string `json:"prefix,omitempty"`
+	// SitePathPrefix defaults PathPrefix to <namespace>/<name> of the site,
+	// so sites sharing a bucket don't overwrite each other's media files.
+	// +optional
+	SitePathPrefix bool `json:"sitePrefix,omitempty"`
+	// ForcePathStyle enables path-style addressing for the bucket, as
+	// required by some S3 compatible object stores (eg. MinIO).
+	// Defaults to virtual-hosted addressing.
+	// +optional
+	ForcePathStyle bool `json:"forcePathStyle,omitempty"`
+	// Region of the S3 bucket. If not specified, AWS_REGION is not set.
+	// +optional
+	Region string `json:"region,omitempty"`
+	// ObjectACL is the canned ACL of the uploaded media objects, passed as
+	// STACK_MEDIA_ACL. If not specified, the media plugin default is used.
+	// +kubebuilder:validation:Enum=private;public-read;public-read-write;authenticated-read;aws-exec-read;bucket-owner-read;bucket-owner-full-control
+	// +optional
+	ObjectACL string `json:"objectACL,omitempty"`
 	// Env variables for accessing S3 bucket. Taken into account are:
-	// ACCESS_KEY, SECRET_ACCESS_KEY
+	// ACCESS_KEY, SECRET_ACCESS_KEY, REGION
 	// +optional
 	// +patchMergeKey=name
 	// +patchStrategy=merge
@@ -214,8 +771,16 @@ type GCSVolumeSource struct {
 	// Bucket for storing media files
 	// +kubebuilder:validation:MinLength=1
 	Bucket string `json:"bucket"`
-	// PathPrefix is the prefix for media files in bucket
+	// PathPrefix is the prefix for media files in bucket. It can reference
+	// the STACK_* and WP_* variables set by the operator, and the site Env
+	// variables, using $(VAR_NAME) expansion, eg. $(STACK_SITE_NAMESPACE)
 	PathPrefix string `json:"prefix,omitempty"`
+	// PredefinedACL is the predefined ACL of the uploaded media objects,
+	// passed as STACK_MEDIA_ACL. If not specified, the media plugin default is
+	// used.
+	// +kubebuilder:validation:Enum=authenticatedRead;bucketOwnerFullControl;bucketOwnerRead;private;projectPrivate;publicRead
+	// +optional
+	PredefinedACL string `json:"predefinedACL,omitempty"`
 	// Env variables for accessing gcs bucket. Taken into account are:
 	// GOOGLE_APPLICATION_CREDENTIALS_JSON
 	// +optional
@@ -224,10 +789,53 @@ type GCSVolumeSource struct {
 	Env []corev1.EnvVar `json:"env,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
 }
 
+// MediaPreSyncSpec is the desired spec of the init container copying media
+// files from the object storage into a local cache, with rclone. The init
+// container gets the media credentials of the wordpress container, including
+// its env, envFrom and volume mounts for file credentials.
+//
+// The operator only fills the cache, which is mounted in the wordpress
+// containers at MountPath: the web server has to be configured to serve the
+// media files from it (eg. with an nginx try_files rule), falling back to the
+// bucket for the files that are missing. The cache is not kept in sync with
+// the bucket once the pod started.
+type MediaPreSyncSpec struct {
+	// Image of the init container, which must provide rclone.
+	// Defaults to docker.io/rclone/rclone:1.57.0
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Prefix of the media files to copy (eg. 2021/), relative to the media
+	// path prefix. Defaults to all of the media files.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+	// MountPath of the cache volume in the wordpress containers, where
+	// files are found under the same path as in the bucket, relative to the
+	// media path prefix.
+	// Defaults to /var/cache/media
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+	// SizeLimit of the cache volume.
+	// +optional
+	SizeLimit *resource.Quantity `json:"sizeLimit,omitempty"`
+	// TimeoutSeconds after which the copy is stopped and the pod starts
+	// with the files copied so far. Copy errors don't block the pod either.
+	// Defaults to 300.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+	// Resources of the init container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
 // CodeVolumeSpec is the desired spec for mounting code into the wordpress
 // runtime container.
 type CodeVolumeSpec struct {
 	// Metadata for the media volume. Currently only labels and annotations are set if a PVC is specified
+	// (eg. for selecting the PVC for scheduled volume snapshots). Annotations
+	// already set in the kubernetes.io, k8s.io or presslabs.org domains
+	// (eg. by the PV controller) are not overwritten, nor removed once removed
+	// from here.
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 	// ReadOnly specifies if the volume should be mounted read-only inside the
 	// wordpress runtime container
@@ -244,6 +852,10 @@ type CodeVolumeSpec struct {
 	// Defaults to: config
 	// +optional
 	ConfigSubPath string `json:"configSubPath,omitempty"`
+	// ConfigGitDir specifies a separate git repo holding the config
+	// directory, which then gets mounted instead of ConfigSubPath.
+	// +optional
+	ConfigGitDir *ConfigGitVolumeSource `json:"configGit,omitempty"`
 	// GitDir specifies the git repo to use for code cloning. It has the highest
 	// level of precedence over EmptyDir, HostPath and PersistentVolumeClaim
 	// +optional
@@ -262,6 +874,10 @@ type CodeVolumeSpec struct {
 // MediaVolumeSpec is the desired spec for handling media files at runtime.
 type MediaVolumeSpec struct {
 	// Metadata for the media volume. Currently only labels and annotations are set if a PVC is specified
+	// (eg. for selecting the PVC for scheduled volume snapshots). Annotations
+	// already set in the kubernetes.io, k8s.io or presslabs.org domains
+	// (eg. by the PV controller) are not overwritten, nor removed once removed
+	// from here.
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 	// ReadOnly specifies if the volume should be mounted read-only inside the
 	// wordpress runtime container
@@ -273,21 +889,41 @@ type MediaVolumeSpec struct {
 	// ContentSubPath specifies where within the media volume, the media files are located.
 	// +optional
 	ContentSubPath string `json:"contentSubPath,omitempty"`
+	// TokenAudience is the audience of the service account token projected
+	// into the wordpress containers for authenticating to the media object
+	// storage (eg. sts.amazonaws.com), set as AWS_WEB_IDENTITY_TOKEN_FILE.
+	// It must be set together with RoleARN.
+	// +optional
+	TokenAudience string `json:"tokenAudience,omitempty"`
+	// RoleARN is the role assumed with the projected service account token,
+	// set as AWS_ROLE_ARN. It must be set together with TokenAudience.
+	// +optional
+	RoleARN string `json:"roleARN,omitempty"`
 	// S3VolumeSource specifies the S3 object storage configuration for media
-	// files. It has the highest level of precedence over EmptyDir, HostPath
-	// and PersistentVolumeClaim
+	// files. It can't be combined with GCSVolumeSource or the volume sources
+	// below (eg. EmptyDir), which are mounted locally.
 	// +optional
 	S3VolumeSource *S3VolumeSource `json:"s3,omitempty"`
 	// GCSVolumeSource specifies the google cloud storage object storage
-	// configuration for media files. It has the highest level of precedence
-	// over EmptyDir, HostPath and PersistentVolumeClaim
+	// configuration for media files. It can't be combined with S3VolumeSource
+	// or the volume sources below (eg. EmptyDir), which are mounted locally.
 	// +optional
 	GCSVolumeSource *GCSVolumeSource `json:"gcs,omitempty"`
+	// PreSync copies media files from the S3 or GCS bucket into a local
+	// cache volume, before the web pods start.
+	// +optional
+	PreSync *MediaPreSyncSpec `json:"preSync,omitempty"`
 	// PersistentVolumeClaim to use if no S3VolumeSource or GCSVolumeSource are
 	// specified
 	// +optional
 	PersistentVolumeClaim *corev1.PersistentVolumeClaimSpec `json:"persistentVolumeClaim,omitempty"`
-	// HostPath to use if no PersistentVolumeClaim is specified
+	// CephFS to use if no PersistentVolumeClaim is specified
+	// +optional
+	CephFS *corev1.CephFSVolumeSource `json:"cephfs,omitempty"`
+	// Glusterfs to use if no CephFS is specified
+	// +optional
+	Glusterfs *corev1.GlusterfsVolumeSource `json:"glusterfs,omitempty"`
+	// HostPath to use if no Glusterfs is specified
 	// +optional
 	HostPath *corev1.HostPathVolumeSource `json:"hostPath,omitempty"`
 	// EmptyDir to use if no HostPath is specified
@@ -307,6 +943,230 @@ type WordpressBootstrapSpec struct {
 	// EnvFrom defines envFrom's which get passed into wordpress bootstrapper
 	// +optional
 	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+	// Attempts is the number of times the install is tried before failing
+	// the bootstrap container. Defaults to 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+	// RetryDelaySeconds is the delay between install attempts. Defaults to 10.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RetryDelaySeconds *int32 `json:"retryDelaySeconds,omitempty"`
+}
+
+// WarmupPath is a local HTTP path of the wordpress container.
+// +kubebuilder:validation:Pattern=`^/[A-Za-z0-9/._~?=&%+-]*$`
+type WarmupPath string
+
+// WarmupSpec is the desired spec for warming up the wordpress container.
+type WarmupSpec struct {
+	// Paths to request, in order, eg. / or /wp-login.php
+	// +kubebuilder:validation:MinItems=1
+	Paths []WarmupPath `json:"paths"`
+}
+
+// MaintenanceWindowSpec is a daily time window, in UTC.
+type MaintenanceWindowSpec struct {
+	// Start of the window, as HH:MM.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// End of the window, as HH:MM. If it is before Start, the window ends on
+	// the next day.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+}
+
+// SharedSocketVolumeSpec is the desired spec for the volume shared by the
+// wordpress container and sidecars.
+type SharedSocketVolumeSpec struct {
+	// MountPath is where the volume is mounted in every container. It must
+	// not collide with the operator managed mounts. Defaults to
+	// /var/run/sockets.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// FPMExporterSpec is the desired spec for the php-fpm exporter sidecar.
+type FPMExporterSpec struct {
+	// Image of the exporter. Defaults to docker.io/hipages/php-fpm_exporter:2.2.0.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Socket is the name of the php-fpm socket, which the image must listen
+	// on in the shared socket volume. Defaults to php-fpm.sock.
+	// +optional
+	Socket string `json:"socket,omitempty"`
+	// StatusPath is the php-fpm pm.status_path. Defaults to /status.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	StatusPath string `json:"statusPath,omitempty"`
+	// Resources of the exporter container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// LivenessProbe of the exporter container. Defaults to a TCP check of
+	// the prometheus port.
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+	// ReadinessProbe of the exporter container. Defaults to a TCP check of
+	// the prometheus port.
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+}
+
+// ProbeDefaultsSpec is the timing of the default readiness and liveness
+// probes. When any field is set, the resulting TimeoutSeconds must be lower
+// than PeriodSeconds * FailureThreshold, including the defaults of the fields
+// left unset (eg. setting only PeriodSeconds requires it above 10, given the
+// default 30s timeout and failure threshold of 3).
+type ProbeDefaultsSpec struct {
+	// TimeoutSeconds after which a probe times out. Defaults to 30.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+	// PeriodSeconds is how often the probes run. Defaults to 5.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+	// FailureThreshold is the number of consecutive failures after which the
+	// pod is unready, or the container gets restarted. Defaults to 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// DebugSpec is the desired spec for the WordPress debug mode.
+type DebugSpec struct {
+	// Enabled turns on the debug mode. Errors get logged to LogPath instead
+	// of being displayed.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// LogPath is the path of the debug log file, relative to the /var/log
+	// volume, so the log gets collected along with the web server ones.
+	// Defaults to wp-debug.log.
+	// +optional
+	LogPath string `json:"logPath,omitempty"`
+}
+
+// MediaReadinessCheckSpec is the desired spec for checking the media storage
+// access on readiness.
+type MediaReadinessCheckSpec struct {
+	// Command checks the media storage, exiting with a non-zero status on
+	// failure. Defaults to listing the media bucket with wp eval, through
+	// the stream wrapper of the stack media plugin.
+	// +optional
+	Command []string `json:"command,omitempty"`
+}
+
+// GitSignatureVerification selects the keys trusted to sign the code.
+type GitSignatureVerification struct {
+	// PublicKeySecret selects the secret key holding the ASCII armored GPG
+	// public keys
+	PublicKeySecret corev1.SecretKeySelector `json:"publicKeySecret"`
+}
+
+// ShutdownOrderSpec orders the termination of the web pod containers by
+// delaying it from their pre-stop hooks. Kubernetes stops the containers
+// concurrently, so the ordering is best-effort.
+type ShutdownOrderSpec struct {
+	// SidecarsDelaySeconds is the time the sidecars wait before stopping,
+	// after the DrainSeconds of the wordpress container, so the pod gets
+	// removed from the service endpoints first. Only sidecars
+	// without a pre-stop hook are delayed, and their image must provide the
+	// sleep command. Defaults to 5.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SidecarsDelaySeconds *int32 `json:"sidecarsDelaySeconds,omitempty"`
+	// WordpressDelaySeconds is the time the wordpress container keeps
+	// running after the sidecars start stopping, after draining. It extends
+	// the pod termination grace period, together with SidecarsDelaySeconds.
+	// Defaults to 10.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	WordpressDelaySeconds *int32 `json:"wordpressDelaySeconds,omitempty"`
+}
+
+// SharedSessionVolumeSpec is the desired spec for the PHP sessions volume.
+// Exactly one of PersistentVolumeClaim and NFS must be set.
+type SharedSessionVolumeSpec struct {
+	// MountPath is where the volume is mounted, set as the PHP session.save_path
+	// through the PHP_SESSION_SAVE_PATH env var. It must not collide with the
+	// other mounts. Defaults to /var/lib/php/sessions.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+	// PersistentVolumeClaim references an existing claim, which must allow
+	// being mounted by all the replicas.
+	// +optional
+	PersistentVolumeClaim *corev1.PersistentVolumeClaimVolumeSource `json:"persistentVolumeClaim,omitempty"`
+	// NFS volume to use if no PersistentVolumeClaim is specified
+	// +optional
+	NFS *corev1.NFSVolumeSource `json:"nfs,omitempty"`
+}
+
+// VaultAgentSpec is the desired spec for rendering secrets with a Vault agent.
+type VaultAgentSpec struct {
+	// Image of the Vault agent. Defaults to docker.io/hashicorp/vault:1.8.4.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// ConfigMapName is the name of the config map holding the agent
+	// configuration in the agent.hcl key. The agent runs with
+	// -exit-after-auth, so its templates must render into MountPath.
+	// +kubebuilder:validation:MinLength=1
+	ConfigMapName string `json:"configMapName"`
+	// MountPath is where the rendered secrets are mounted. Defaults to
+	// /vault/secrets.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+	// SecretsFile is the name of the file rendered by the agent, whose path
+	// is exposed to the wordpress containers as VAULT_SECRETS_FILE. Defaults
+	// to secrets.
+	// +optional
+	SecretsFile string `json:"secretsFile,omitempty"`
+	// Env defines env variables which get passed to the agent (eg.
+	// VAULT_ADDR).
+	// +optional
+	// +patchMergeKey=name
+	// +patchStrategy=merge
+	Env []corev1.EnvVar `json:"env,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+}
+
+// WPCronSpec controls the triggering of wp-cron.
+type WPCronSpec struct {
+	// Suspend stops the operator from triggering wp-cron until it is cleared.
+	// The trigger requests for a site are never run concurrently.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// FileMount mounts a single ConfigMap or Secret key at the given path.
+// Exactly one of ConfigMapKeyRef or SecretKeyRef must be set.
+type FileMount struct {
+	// Path is the absolute path where the file is mounted
+	// +kubebuilder:validation:Pattern=`^/`
+	Path string `json:"path"`
+	// ConfigMapKeyRef selects a key of a ConfigMap
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// SecretKeyRef selects a key of a Secret
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// MigrationsSpec defines the migration step which runs in web pods, after
+// the code is in place and before the wordpress container starts.
+type MigrationsSpec struct {
+	// Command is the migration command, eg. `wp core update-db`
+	// +kubebuilder:validation:MinItems=1
+	Command []string `json:"command"`
+	// Env defines additional environment variables for the migration container
+	// +optional
+	// +patchMergeKey=name
+	// +patchStrategy=merge
+	Env []corev1.EnvVar `json:"env,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	// EnvFrom defines additional envFrom's for the migration container
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
 }
 
 // WordpressStatus defines the observed state of Wordpress.
@@ -27,10 +27,35 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanarySpec) DeepCopyInto(out *CanarySpec) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanarySpec.
+func (in *CanarySpec) DeepCopy() *CanarySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CanarySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CodeVolumeSpec) DeepCopyInto(out *CodeVolumeSpec) {
 	*out = *in
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.ConfigGitDir != nil {
+		in, out := &in.ConfigGitDir, &out.ConfigGitDir
+		*out = new(ConfigGitVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.GitDir != nil {
 		in, out := &in.GitDir, &out.GitDir
 		*out = new(GitVolumeSource)
@@ -63,6 +88,101 @@ func (in *CodeVolumeSpec) DeepCopy() *CodeVolumeSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigGitVolumeSource) DeepCopyInto(out *ConfigGitVolumeSource) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]v1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigGitVolumeSource.
+func (in *ConfigGitVolumeSource) DeepCopy() *ConfigGitVolumeSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigGitVolumeSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DebugSpec) DeepCopyInto(out *DebugSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugSpec.
+func (in *DebugSpec) DeepCopy() *DebugSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DebugSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FPMExporterSpec) DeepCopyInto(out *FPMExporterSpec) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.LivenessProbe != nil {
+		in, out := &in.LivenessProbe, &out.LivenessProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReadinessProbe != nil {
+		in, out := &in.ReadinessProbe, &out.ReadinessProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FPMExporterSpec.
+func (in *FPMExporterSpec) DeepCopy() *FPMExporterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FPMExporterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileMount) DeepCopyInto(out *FileMount) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileMount.
+func (in *FileMount) DeepCopy() *FileMount {
+	if in == nil {
+		return nil
+	}
+	out := new(FileMount)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GCSVolumeSource) DeepCopyInto(out *GCSVolumeSource) {
 	*out = *in
@@ -85,6 +205,22 @@ func (in *GCSVolumeSource) DeepCopy() *GCSVolumeSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitSignatureVerification) DeepCopyInto(out *GitSignatureVerification) {
+	*out = *in
+	in.PublicKeySecret.DeepCopyInto(&out.PublicKeySecret)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSignatureVerification.
+func (in *GitSignatureVerification) DeepCopy() *GitSignatureVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(GitSignatureVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitVolumeSource) DeepCopyInto(out *GitVolumeSource) {
 	*out = *in
@@ -95,6 +231,21 @@ func (in *GitVolumeSource) DeepCopyInto(out *GitVolumeSource) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SSHKeySecret != nil {
+		in, out := &in.SSHKeySecret, &out.SSHKeySecret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CABundleSecret != nil {
+		in, out := &in.CABundleSecret, &out.CABundleSecret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VerifySignature != nil {
+		in, out := &in.VerifySignature, &out.VerifySignature
+		*out = new(GitSignatureVerification)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.EnvFrom != nil {
 		in, out := &in.EnvFrom, &out.EnvFrom
 		*out = make([]v1.EnvFromSource, len(*in))
@@ -102,11 +253,21 @@ func (in *GitVolumeSource) DeepCopyInto(out *GitVolumeSource) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PostCloneCommand != nil {
+		in, out := &in.PostCloneCommand, &out.PostCloneCommand
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.EmptyDir != nil {
 		in, out := &in.EmptyDir, &out.EmptyDir
 		*out = new(v1.EmptyDirVolumeSource)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.SizeLimit != nil {
+		in, out := &in.SizeLimit, &out.SizeLimit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitVolumeSource.
@@ -119,6 +280,67 @@ func (in *GitVolumeSource) DeepCopy() *GitVolumeSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowSpec) DeepCopyInto(out *MaintenanceWindowSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowSpec.
+func (in *MaintenanceWindowSpec) DeepCopy() *MaintenanceWindowSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MediaPreSyncSpec) DeepCopyInto(out *MediaPreSyncSpec) {
+	*out = *in
+	if in.SizeLimit != nil {
+		in, out := &in.SizeLimit, &out.SizeLimit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MediaPreSyncSpec.
+func (in *MediaPreSyncSpec) DeepCopy() *MediaPreSyncSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MediaPreSyncSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MediaReadinessCheckSpec) DeepCopyInto(out *MediaReadinessCheckSpec) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MediaReadinessCheckSpec.
+func (in *MediaReadinessCheckSpec) DeepCopy() *MediaReadinessCheckSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MediaReadinessCheckSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MediaVolumeSpec) DeepCopyInto(out *MediaVolumeSpec) {
 	*out = *in
@@ -133,11 +355,26 @@ func (in *MediaVolumeSpec) DeepCopyInto(out *MediaVolumeSpec) {
 		*out = new(GCSVolumeSource)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PreSync != nil {
+		in, out := &in.PreSync, &out.PreSync
+		*out = new(MediaPreSyncSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PersistentVolumeClaim != nil {
 		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
 		*out = new(v1.PersistentVolumeClaimSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CephFS != nil {
+		in, out := &in.CephFS, &out.CephFS
+		*out = new(v1.CephFSVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Glusterfs != nil {
+		in, out := &in.Glusterfs, &out.Glusterfs
+		*out = new(v1.GlusterfsVolumeSource)
+		**out = **in
+	}
 	if in.HostPath != nil {
 		in, out := &in.HostPath, &out.HostPath
 		*out = new(v1.HostPathVolumeSource)
@@ -160,9 +397,65 @@ func (in *MediaVolumeSpec) DeepCopy() *MediaVolumeSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationsSpec) DeepCopyInto(out *MigrationsSpec) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]v1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationsSpec.
+func (in *MigrationsSpec) DeepCopy() *MigrationsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbeDefaultsSpec) DeepCopyInto(out *ProbeDefaultsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeDefaultsSpec.
+func (in *ProbeDefaultsSpec) DeepCopy() *ProbeDefaultsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbeDefaultsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RouteSpec) DeepCopyInto(out *RouteSpec) {
 	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteSpec.
@@ -197,6 +490,143 @@ func (in *S3VolumeSource) DeepCopy() *S3VolumeSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedSessionVolumeSpec) DeepCopyInto(out *SharedSessionVolumeSpec) {
+	*out = *in
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(v1.PersistentVolumeClaimVolumeSource)
+		**out = **in
+	}
+	if in.NFS != nil {
+		in, out := &in.NFS, &out.NFS
+		*out = new(v1.NFSVolumeSource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedSessionVolumeSpec.
+func (in *SharedSessionVolumeSpec) DeepCopy() *SharedSessionVolumeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedSessionVolumeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedSocketVolumeSpec) DeepCopyInto(out *SharedSocketVolumeSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedSocketVolumeSpec.
+func (in *SharedSocketVolumeSpec) DeepCopy() *SharedSocketVolumeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedSocketVolumeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShutdownOrderSpec) DeepCopyInto(out *ShutdownOrderSpec) {
+	*out = *in
+	if in.SidecarsDelaySeconds != nil {
+		in, out := &in.SidecarsDelaySeconds, &out.SidecarsDelaySeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.WordpressDelaySeconds != nil {
+		in, out := &in.WordpressDelaySeconds, &out.WordpressDelaySeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShutdownOrderSpec.
+func (in *ShutdownOrderSpec) DeepCopy() *ShutdownOrderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ShutdownOrderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VPASpec) DeepCopyInto(out *VPASpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VPASpec.
+func (in *VPASpec) DeepCopy() *VPASpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VPASpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultAgentSpec) DeepCopyInto(out *VaultAgentSpec) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultAgentSpec.
+func (in *VaultAgentSpec) DeepCopy() *VaultAgentSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultAgentSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WPCronSpec) DeepCopyInto(out *WPCronSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WPCronSpec.
+func (in *WPCronSpec) DeepCopy() *WPCronSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WPCronSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WarmupSpec) DeepCopyInto(out *WarmupSpec) {
+	*out = *in
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]WarmupPath, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WarmupSpec.
+func (in *WarmupSpec) DeepCopy() *WarmupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WarmupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Wordpress) DeepCopyInto(out *Wordpress) {
 	*out = *in
@@ -241,6 +671,11 @@ func (in *WordpressBootstrapSpec) DeepCopyInto(out *WordpressBootstrapSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RetryDelaySeconds != nil {
+		in, out := &in.RetryDelaySeconds, &out.RetryDelaySeconds
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WordpressBootstrapSpec.
@@ -310,6 +745,11 @@ func (in *WordpressSpec) DeepCopyInto(out *WordpressSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindowSpec)
+		**out = **in
+	}
 	if in.Domains != nil {
 		in, out := &in.Domains, &out.Domains
 		*out = make([]Domain, len(*in))
@@ -318,18 +758,40 @@ func (in *WordpressSpec) DeepCopyInto(out *WordpressSpec) {
 	if in.Routes != nil {
 		in, out := &in.Routes, &out.Routes
 		*out = make([]RouteSpec, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.ImagePullSecrets != nil {
 		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
 		*out = make([]v1.LocalObjectReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.JobProbe != nil {
+		in, out := &in.JobProbe, &out.JobProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.DeploymentStrategy != nil {
 		in, out := &in.DeploymentStrategy, &out.DeploymentStrategy
 		*out = new(appsv1.DeploymentStrategy)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.VPA != nil {
+		in, out := &in.VPA, &out.VPA
+		*out = new(VPASpec)
+		**out = **in
+	}
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(CanarySpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.CodeVolumeSpec != nil {
 		in, out := &in.CodeVolumeSpec, &out.CodeVolumeSpec
 		*out = new(CodeVolumeSpec)
@@ -362,11 +824,51 @@ func (in *WordpressSpec) DeepCopyInto(out *WordpressSpec) {
 		*out = new(v1.Probe)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.StartupProbe != nil {
+		in, out := &in.StartupProbe, &out.StartupProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProbeHeaders != nil {
+		in, out := &in.ProbeHeaders, &out.ProbeHeaders
+		*out = make([]v1.HTTPHeader, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProbeDefaults != nil {
+		in, out := &in.ProbeDefaults, &out.ProbeDefaults
+		*out = new(ProbeDefaultsSpec)
+		**out = **in
+	}
+	if in.MediaReadinessCheck != nil {
+		in, out := &in.MediaReadinessCheck, &out.MediaReadinessCheck
+		*out = new(MediaReadinessCheckSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ShutdownOrder != nil {
+		in, out := &in.ShutdownOrder, &out.ShutdownOrder
+		*out = new(ShutdownOrderSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Warmup != nil {
+		in, out := &in.Warmup, &out.Warmup
+		*out = new(WarmupSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.WordpressBootstrapSpec != nil {
 		in, out := &in.WordpressBootstrapSpec, &out.WordpressBootstrapSpec
 		*out = new(WordpressBootstrapSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Migrations != nil {
+		in, out := &in.Migrations, &out.Migrations
+		*out = new(MigrationsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WPCron != nil {
+		in, out := &in.WPCron, &out.WPCron
+		*out = new(WPCronSpec)
+		**out = **in
+	}
 	if in.VolumeMounts != nil {
 		in, out := &in.VolumeMounts, &out.VolumeMounts
 		*out = make([]v1.VolumeMount, len(*in))
@@ -374,6 +876,13 @@ func (in *WordpressSpec) DeepCopyInto(out *WordpressSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]FileMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
 		*out = make([]v1.EnvVar, len(*in))
@@ -381,6 +890,11 @@ func (in *WordpressSpec) DeepCopyInto(out *WordpressSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Debug != nil {
+		in, out := &in.Debug, &out.Debug
+		*out = new(DebugSpec)
+		**out = **in
+	}
 	if in.EnvFrom != nil {
 		in, out := &in.EnvFrom, &out.EnvFrom
 		*out = make([]v1.EnvFromSource, len(*in))
@@ -403,11 +917,43 @@ func (in *WordpressSpec) DeepCopyInto(out *WordpressSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DefaultTolerationSeconds != nil {
+		in, out := &in.DefaultTolerationSeconds, &out.DefaultTolerationSeconds
+		*out = new(int64)
+		**out = **in
+	}
 	if in.Affinity != nil {
 		in, out := &in.Affinity, &out.Affinity
 		*out = new(v1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PodOverhead != nil {
+		in, out := &in.PodOverhead, &out.PodOverhead
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.EnableServiceLinks != nil {
+		in, out := &in.EnableServiceLinks, &out.EnableServiceLinks
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ShareProcessNamespace != nil {
+		in, out := &in.ShareProcessNamespace, &out.ShareProcessNamespace
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RunAsGroup != nil {
+		in, out := &in.RunAsGroup, &out.RunAsGroup
+		*out = new(int64)
+		**out = **in
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.IngressAnnotations != nil {
 		in, out := &in.IngressAnnotations, &out.IngressAnnotations
 		*out = make(map[string]string, len(*in))
@@ -415,6 +961,20 @@ func (in *WordpressSpec) DeepCopyInto(out *WordpressSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.ServiceAnnotations != nil {
+		in, out := &in.ServiceAnnotations, &out.ServiceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DeploymentAnnotations != nil {
+		in, out := &in.DeploymentAnnotations, &out.DeploymentAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.InitContainers != nil {
 		in, out := &in.InitContainers, &out.InitContainers
 		*out = make([]v1.Container, len(*in))
@@ -422,6 +982,13 @@ func (in *WordpressSpec) DeepCopyInto(out *WordpressSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PreVolumeInitContainers != nil {
+		in, out := &in.PreVolumeInitContainers, &out.PreVolumeInitContainers
+		*out = make([]v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Sidecars != nil {
 		in, out := &in.Sidecars, &out.Sidecars
 		*out = make([]v1.Container, len(*in))
@@ -429,6 +996,31 @@ func (in *WordpressSpec) DeepCopyInto(out *WordpressSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SharedSocketVolume != nil {
+		in, out := &in.SharedSocketVolume, &out.SharedSocketVolume
+		*out = new(SharedSocketVolumeSpec)
+		**out = **in
+	}
+	if in.SharedSessionVolume != nil {
+		in, out := &in.SharedSessionVolume, &out.SharedSessionVolume
+		*out = new(SharedSessionVolumeSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VaultAgent != nil {
+		in, out := &in.VaultAgent, &out.VaultAgent
+		*out = new(VaultAgentSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FPMExporter != nil {
+		in, out := &in.FPMExporter, &out.FPMExporter
+		*out = new(FPMExporterSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DefaultVolumeSizeLimit != nil {
+		in, out := &in.DefaultVolumeSizeLimit, &out.DefaultVolumeSizeLimit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WordpressSpec.
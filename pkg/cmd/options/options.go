@@ -17,11 +17,15 @@ limitations under the License.
 package options
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/spf13/pflag"
 )
@@ -51,6 +55,25 @@ var (
 
 	// HealthProbeBindAddress is the TCP address that the controller should bind to for serving health probes.
 	HealthProbeBindAddress = ":8081"
+
+	// MinReplicas is the minimum number of web pods for a site. Lower replica counts are raised to it.
+	MinReplicas int32 = 1
+
+	// CommonLabels are set on all the objects created by the operator. Labels set by the operator take precedence.
+	CommonLabels = map[string]string{}
+
+	// DefaultNodeSelector is merged into the node selector of WordPress pods. Site node selector keys take precedence.
+	DefaultNodeSelector = map[string]string{}
+
+	// RegistryMirrors maps registry prefixes (eg. docker.io or gcr.io/google-containers) to the mirrors
+	// the images of WordPress pods are pulled from instead. Images from other registries are left as they are.
+	RegistryMirrors = map[string]string{}
+
+	// AllowedGitSchemes restricts the git repository URL schemes sites may clone code from (eg. ssh). All are allowed if empty.
+	AllowedGitSchemes = []string{}
+
+	// DefaultResources are the resources set on the wordpress container when a site doesn't specify any.
+	DefaultResources = corev1.ResourceRequirements{}
 )
 
 func namespace() string {
@@ -71,6 +94,55 @@ func namespace() string {
 	return corev1.NamespaceDefault
 }
 
+var errInvalidResource = errors.New("invalid resource")
+
+// resourceListValue implements pflag.Value for a corev1.ResourceList in the
+// form of cpu=100m,memory=128Mi.
+type resourceListValue struct {
+	list *corev1.ResourceList
+}
+
+func (v resourceListValue) String() string {
+	pairs := []string{}
+	for name, q := range *v.list {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, q.String()))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (v resourceListValue) Set(val string) error {
+	list := corev1.ResourceList{}
+
+	for _, pair := range strings.Split(val, ",") {
+		if pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("%w: %q, expected <name>=<quantity>", errInvalidResource, pair)
+		}
+
+		q, err := resource.ParseQuantity(kv[1])
+		if err != nil {
+			return fmt.Errorf("invalid quantity for resource %q: %w", kv[0], err)
+		}
+
+		list[corev1.ResourceName(kv[0])] = q
+	}
+
+	*v.list = list
+
+	return nil
+}
+
+func (v resourceListValue) Type() string {
+	return "resourceList"
+}
+
 // AddToFlagSet set command line arguments.
 func AddToFlagSet(flag *pflag.FlagSet) {
 	flag.StringVar(&GitCloneImage, "git-clone-image", GitCloneImage, "The image used when cloning code from git.")
@@ -82,4 +154,12 @@ func AddToFlagSet(flag *pflag.FlagSet) {
 	flag.StringVar(&MetricsBindAddress, "metrics-addr", MetricsBindAddress, "The TCP address that the controller should bind to for serving prometheus metrics."+
 		" It can be set to \"0\" to disable the metrics serving.")
 	flag.StringVar(&HealthProbeBindAddress, "healthz-addr", HealthProbeBindAddress, "The TCP address that the controller should bind to for serving health probes.")
+	flag.Int32Var(&MinReplicas, "min-replicas", MinReplicas, "The minimum number of web pods for a WordPress site.")
+	flag.StringToStringVar(&CommonLabels, "common-labels", CommonLabels, "Labels to set on all the objects created by the operator (eg. team=web,environment=production).")
+	flag.StringToStringVar(&DefaultNodeSelector, "default-node-selector", DefaultNodeSelector, "The default node selector for WordPress pods (eg. workload=wordpress).")
+	flag.StringToStringVar(&RegistryMirrors, "registry-mirrors", RegistryMirrors, "Registry prefixes to rewrite the images of WordPress pods from, to their mirror"+
+		" (eg. docker.io=registry.local/docker-hub).")
+	flag.StringSliceVar(&AllowedGitSchemes, "allowed-git-schemes", AllowedGitSchemes, "The git repository URL schemes sites may clone code from (eg. ssh). All are allowed if empty.")
+	flag.Var(resourceListValue{&DefaultResources.Requests}, "default-resources-requests", "The default resource requests for the wordpress container (eg. cpu=100m,memory=128Mi).")
+	flag.Var(resourceListValue{&DefaultResources.Limits}, "default-resources-limits", "The default resource limits for the wordpress container (eg. cpu=1,memory=512Mi).")
 }
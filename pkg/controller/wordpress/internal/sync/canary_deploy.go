@@ -0,0 +1,85 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"errors"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/presslabs/controller-util/syncer"
+
+	"github.com/bitpoke/wordpress-operator/pkg/internal/wordpress"
+)
+
+var errCanaryNotDefined = errors.New(".spec.canary is not defined")
+
+// NewCanaryDeploymentSyncer returns a new sync.Interface for reconciling the canary web Deployment.
+func NewCanaryDeploymentSyncer(wp *wordpress.Wordpress, secret *corev1.Secret, c client.Client) syncer.Interface {
+	objLabels := wp.ComponentLabels(wordpress.WordpressCanaryDeployment)
+
+	obj := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      wp.ComponentName(wordpress.WordpressCanaryDeployment),
+			Namespace: wp.Namespace,
+		},
+	}
+
+	return syncer.NewObjectSyncer("CanaryDeployment", wp.Unwrap(), obj, c, func() error {
+		obj.Labels = labels.Merge(labels.Merge(obj.Labels, objLabels), controllerLabels)
+
+		if wp.Spec.Canary == nil {
+			return errCanaryNotDefined
+		}
+
+		if len(obj.Annotations) == 0 {
+			obj.Annotations = make(map[string]string)
+		}
+		obj.Annotations["wordpress.presslabs.org/canary-weight"] = fmt.Sprintf("%d", wp.Spec.Canary.Weight)
+
+		err := syncPodTemplate(&obj.Spec.Template, wp, wp.CanaryPodTemplateSpec(), secret, c)
+		if err != nil {
+			return err
+		}
+
+		selector, ok := syncSelector(obj, obj.Spec.Selector, metav1.SetAsLabelSelector(wp.CanaryPodSelectorLabels()),
+			obj.Spec.Template.Labels, wordpress.SelectorChanged)
+		if !ok {
+			return errImmutableDeploymentSelector
+		}
+		obj.Spec.Selector = selector
+
+		if wp.Spec.Canary.Replicas != nil {
+			obj.Spec.Replicas = wp.Spec.Canary.Replicas
+		}
+
+		if wp.Spec.DeploymentStrategy != nil {
+			obj.Spec.Strategy = *wp.Spec.DeploymentStrategy
+		}
+
+		if wp.Spec.ProgressDeadlineSeconds != nil {
+			obj.Spec.ProgressDeadlineSeconds = wp.Spec.ProgressDeadlineSeconds
+		}
+
+		return nil
+	})
+}
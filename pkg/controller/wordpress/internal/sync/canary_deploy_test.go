@@ -0,0 +1,74 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/presslabs/controller-util/syncer"
+
+	wordpressv1alpha1 "github.com/bitpoke/wordpress-operator/pkg/apis/wordpress/v1alpha1"
+	"github.com/bitpoke/wordpress-operator/pkg/internal/wordpress"
+)
+
+var _ = Describe("The canary deployment syncer", func() {
+	var (
+		wp  *wordpress.Wordpress
+		s   *syncer.ObjectSyncer
+		obj *appsv1.Deployment
+	)
+
+	BeforeEach(func() {
+		replicas := int32(1)
+		wp = wordpress.New(&wordpressv1alpha1.Wordpress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: wordpressv1alpha1.WordpressSpec{
+				Routes: []wordpressv1alpha1.RouteSpec{{Domain: "test.com"}},
+				Canary: &wordpressv1alpha1.CanarySpec{Image: "canary:latest", Weight: 10, Replicas: &replicas},
+			},
+		})
+		wp.SetDefaults()
+
+		s = NewCanaryDeploymentSyncer(wp, &corev1.Secret{}, nil).(*syncer.ObjectSyncer)
+		obj = s.Obj.(*appsv1.Deployment)
+	})
+
+	It("should select only the canary pods", func() {
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Name).To(Equal("test-canary"))
+		Expect(obj.Annotations).To(HaveKeyWithValue("wordpress.presslabs.org/canary-weight", "10"))
+		Expect(*obj.Spec.Replicas).To(Equal(int32(1)))
+		Expect(obj.Spec.Template.Spec.Containers[0].Image).To(Equal("canary:latest"))
+
+		selector, err := metav1.LabelSelectorAsSelector(obj.Spec.Selector)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(selector.Matches(labels.Set(obj.Spec.Template.Labels))).To(BeTrue())
+		Expect(selector.Matches(labels.Set(wp.WebPodTemplateSpec().Labels))).To(BeFalse())
+	})
+
+	It("should fail when the canary is not defined", func() {
+		wp.Spec.Canary = nil
+
+		Expect(s.SyncFn()).To(MatchError(errCanaryNotDefined))
+	})
+})
@@ -16,6 +16,91 @@ limitations under the License.
 
 package sync
 
+import (
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
 var controllerLabels = map[string]string{
 	"app.kubernetes.io/managed-by": "wordpress-operator.presslabs.org",
 }
+
+// appliedAnnotationsKey records the keys of the user defined annotations set
+// on an object, so they get removed once removed from the spec.
+const appliedAnnotationsKey = "wordpress.presslabs.org/applied-annotations"
+
+// syncSelector returns the selector of a workload. New workloads get the
+// given selector, built on the stable selector labels, while existing ones
+// keep their immutable selector, as long as changed reports it still fits
+// the pod labels.
+func syncSelector(obj metav1.Object, current, selector *metav1.LabelSelector, podLabels labels.Set,
+	changed func(*metav1.LabelSelector, labels.Set) bool) (*metav1.LabelSelector, bool) {
+	if created := obj.GetCreationTimestamp(); created.IsZero() {
+		return selector, true
+	}
+
+	return current, !changed(current, podLabels)
+}
+
+// mergeAnnotations merges the user defined annotations into the current ones,
+// except for the reserved annotations already set (eg. by the PV controller),
+// which take precedence.
+func mergeAnnotations(current, annotations map[string]string) map[string]string {
+	return applyAnnotations(current, annotations, isReservedAnnotation)
+}
+
+// applyAnnotations sets the user defined annotations over the current ones,
+// except for the current ones to keep, and removes the previously applied
+// ones which are no longer defined.
+func applyAnnotations(current, annotations map[string]string, keep func(key string) bool) map[string]string {
+	previous := current[appliedAnnotationsKey]
+	if len(annotations) == 0 && previous == "" {
+		return current
+	}
+
+	out := labels.Merge(current, nil)
+
+	for _, k := range strings.Split(previous, ",") {
+		if _, ok := annotations[k]; !ok && !keep(k) {
+			delete(out, k)
+		}
+	}
+
+	applied := []string{}
+
+	for k, v := range annotations {
+		if _, ok := current[k]; ok && keep(k) {
+			continue
+		}
+
+		out[k] = v
+		applied = append(applied, k)
+	}
+
+	delete(out, appliedAnnotationsKey)
+
+	if len(applied) > 0 {
+		sort.Strings(applied)
+		out[appliedAnnotationsKey] = strings.Join(applied, ",")
+	}
+
+	return out
+}
+
+func isReservedAnnotation(key string) bool {
+	i := strings.Index(key, "/")
+	if i < 0 {
+		return false
+	}
+
+	for _, domain := range []string{"kubernetes.io", "k8s.io", "presslabs.org"} {
+		if prefix := key[:i]; prefix == domain || strings.HasSuffix(prefix, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
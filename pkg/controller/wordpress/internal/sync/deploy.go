@@ -17,13 +17,20 @@ limitations under the License.
 package sync
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
-	"reflect"
+	"fmt"
+	"sort"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/appscode/mergo"
@@ -37,7 +44,8 @@ import (
 var errImmutableDeploymentSelector = errors.New("deployment selector is immutable")
 
 // NewDeploymentSyncer returns a new sync.Interface for reconciling web Deployment.
-func NewDeploymentSyncer(wp *wordpress.Wordpress, secret *corev1.Secret, c client.Client) syncer.Interface {
+// The given time is checked against the site's maintenance window.
+func NewDeploymentSyncer(wp *wordpress.Wordpress, secret *corev1.Secret, c client.Client, now time.Time) syncer.Interface {
 	objLabels := wp.ComponentLabels(wordpress.WordpressDeployment)
 
 	obj := &appsv1.Deployment{
@@ -49,32 +57,30 @@ func NewDeploymentSyncer(wp *wordpress.Wordpress, secret *corev1.Secret, c clien
 
 	return syncer.NewObjectSyncer("Deployment", wp.Unwrap(), obj, c, func() error {
 		obj.Labels = labels.Merge(labels.Merge(obj.Labels, objLabels), controllerLabels)
+		obj.Annotations = mergeAnnotations(obj.Annotations, wp.Spec.DeploymentAnnotations)
 
-		template := wp.WebPodTemplateSpec()
+		// only existing deployments get paused for maintenance, new ones must
+		// roll out their pods first
+		inMaintenance, _ := wp.InMaintenanceWindow(now)
+		obj.Spec.Paused = wp.Spec.Paused || (inMaintenance && !obj.ObjectMeta.CreationTimestamp.IsZero())
 
-		if len(template.Annotations) == 0 {
-			template.Annotations = make(map[string]string)
-		}
-		template.Annotations["wordpress.presslabs.org/secretVersion"] = secret.ResourceVersion
-
-		obj.Spec.Template.ObjectMeta = template.ObjectMeta
+		// a paused site keeps its existing deployment, only scaling it
+		if wp.Spec.Paused && !obj.ObjectMeta.CreationTimestamp.IsZero() {
+			obj.Spec.Replicas = wp.Spec.Replicas
 
-		selector := metav1.SetAsLabelSelector(wp.WebPodLabels())
-		if !reflect.DeepEqual(selector, obj.Spec.Selector) {
-			if obj.ObjectMeta.CreationTimestamp.IsZero() {
-				obj.Spec.Selector = selector
-			} else {
-				return errImmutableDeploymentSelector
-			}
+			return nil
 		}
 
-		err := mergo.Merge(&obj.Spec.Template.Spec, template.Spec, mergo.WithTransformers(transformers.PodSpec))
+		err := syncPodTemplate(&obj.Spec.Template, wp, wp.WebPodTemplateSpec(), secret, c)
 		if err != nil {
 			return err
 		}
 
-		obj.Spec.Template.Spec.NodeSelector = wp.Spec.NodeSelector
-		obj.Spec.Template.Spec.Tolerations = wp.Spec.Tolerations
+		selector, ok := syncSelector(obj, obj.Spec.Selector, wp.WebPodSelector(), obj.Spec.Template.Labels, wp.WebSelectorChanged)
+		if !ok {
+			return errImmutableDeploymentSelector
+		}
+		obj.Spec.Selector = selector
 
 		if wp.Spec.Replicas != nil {
 			obj.Spec.Replicas = wp.Spec.Replicas
@@ -84,6 +90,93 @@ func NewDeploymentSyncer(wp *wordpress.Wordpress, secret *corev1.Secret, c clien
 			obj.Spec.Strategy = *wp.Spec.DeploymentStrategy
 		}
 
+		if wp.Spec.ProgressDeadlineSeconds != nil {
+			obj.Spec.ProgressDeadlineSeconds = wp.Spec.ProgressDeadlineSeconds
+		}
+
 		return nil
 	})
 }
+
+// syncPodTemplate merges the generated pod template into the workload's pod template.
+func syncPodTemplate(out *corev1.PodTemplateSpec, wp *wordpress.Wordpress, template corev1.PodTemplateSpec,
+	secret *corev1.Secret, c client.Client) error {
+	if len(template.Annotations) == 0 {
+		template.Annotations = make(map[string]string)
+	}
+	template.Annotations["wordpress.presslabs.org/secretVersion"] = secret.ResourceVersion
+
+	if wp.Spec.RestartOnSecretChange {
+		hash, err := referencedSecretsHash(c, wp)
+		if err != nil {
+			return err
+		}
+
+		template.Annotations["wordpress.presslabs.org/referencedSecretsHash"] = hash
+	}
+
+	out.ObjectMeta = template.ObjectMeta
+
+	err := mergo.Merge(&out.Spec, template.Spec, mergo.WithTransformers(transformers.PodSpec))
+	if err != nil {
+		return err
+	}
+
+	// a removed liveness probe doesn't get merged over the existing one
+	for i := range out.Spec.Containers {
+		for j := range template.Spec.Containers {
+			if out.Spec.Containers[i].Name == template.Spec.Containers[j].Name {
+				out.Spec.Containers[i].LivenessProbe = template.Spec.Containers[j].LivenessProbe
+			}
+		}
+	}
+
+	out.Spec.NodeSelector = template.Spec.NodeSelector
+	out.Spec.Tolerations = template.Spec.Tolerations
+	out.Spec.Affinity = template.Spec.Affinity
+	out.Spec.Overhead = template.Spec.Overhead
+	out.Spec.ImagePullSecrets = template.Spec.ImagePullSecrets
+	out.Spec.EnableServiceLinks = template.Spec.EnableServiceLinks
+	out.Spec.ShareProcessNamespace = template.Spec.ShareProcessNamespace
+	out.Spec.TerminationGracePeriodSeconds = template.Spec.TerminationGracePeriodSeconds
+
+	return nil
+}
+
+// referencedSecretsHash hashes the data of the secrets referenced in the site
+// envFrom, so changing it rolls out the pods.
+func referencedSecretsHash(c client.Client, wp *wordpress.Wordpress) (string, error) {
+	h := sha256.New()
+
+	for _, src := range wp.Spec.EnvFrom {
+		if src.SecretRef == nil {
+			continue
+		}
+
+		secret := &corev1.Secret{}
+		key := types.NamespacedName{Name: src.SecretRef.Name, Namespace: wp.Namespace}
+
+		fmt.Fprintf(h, "%s\n", key.Name)
+
+		err := c.Get(context.TODO(), key, secret)
+		if k8serrors.IsNotFound(err) {
+			// optional secrets may be missing
+			continue
+		} else if err != nil {
+			return "", err
+		}
+
+		keys := make([]string, 0, len(secret.Data))
+		for k := range secret.Data {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			fmt.Fprintf(h, "%s=%x\n", k, secret.Data[k])
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/presslabs/controller-util/syncer"
+
+	wordpressv1alpha1 "github.com/bitpoke/wordpress-operator/pkg/apis/wordpress/v1alpha1"
+	"github.com/bitpoke/wordpress-operator/pkg/internal/wordpress"
+)
+
+var _ = Describe("The deployment syncer", func() {
+	var (
+		wp  *wordpress.Wordpress
+		s   *syncer.ObjectSyncer
+		obj *appsv1.Deployment
+
+		now = time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		wp = wordpress.New(&wordpressv1alpha1.Wordpress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: wordpressv1alpha1.WordpressSpec{
+				Routes: []wordpressv1alpha1.RouteSpec{{Domain: "test.com"}},
+			},
+		})
+		wp.SetDefaults()
+
+		s = NewDeploymentSyncer(wp, &corev1.Secret{}, nil, now).(*syncer.ObjectSyncer)
+		obj = s.Obj.(*appsv1.Deployment)
+	})
+
+	It("should select new deployments on the stable labels", func() {
+		wp.Spec.PodMetadata = &metav1.ObjectMeta{Labels: map[string]string{"team": "blog"}}
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Selector.MatchLabels).To(Equal(map[string]string{
+			"app.kubernetes.io/name":      "wordpress",
+			"app.kubernetes.io/instance":  "test",
+			"app.kubernetes.io/component": "web",
+		}))
+		Expect(obj.Spec.Template.Labels).To(HaveKeyWithValue("team", "blog"))
+		Expect(obj.Spec.Template.Labels).To(HaveKeyWithValue("app.kubernetes.io/part-of", "wordpress"))
+	})
+
+	It("should keep the selector of existing deployments while it matches the pods", func() {
+		obj.CreationTimestamp = metav1.Now()
+		obj.Spec.Selector = metav1.SetAsLabelSelector(wp.WebPodLabels())
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Selector.MatchLabels).To(Equal(map[string]string(wp.WebPodLabels())))
+
+		wp.ObjectMeta.Labels = map[string]string{"app.kubernetes.io/part-of": "blog"}
+		Expect(s.SyncFn()).To(MatchError(errImmutableDeploymentSelector))
+	})
+
+	It("should not keep the selector of upgraded deployments matching the canary pods", func() {
+		obj.CreationTimestamp = metav1.Now()
+		obj.Spec.Selector = metav1.SetAsLabelSelector(wp.WebPodLabels())
+
+		Expect(s.SyncFn()).To(Succeed())
+
+		wp.Spec.Canary = &wordpressv1alpha1.CanarySpec{Image: "docker.io/bitpoke/wordpress-runtime:canary"}
+		Expect(wp.WebSelectorChanged(obj.Spec.Selector, obj.Spec.Template.Labels)).To(BeTrue())
+		Expect(s.SyncFn()).To(MatchError(errImmutableDeploymentSelector))
+
+		// the recreated deployment leaves out the canary pods
+		obj.Spec.Selector = wp.WebPodSelector()
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Selector).To(Equal(wp.WebPodSelector()))
+	})
+
+	It("should set the deployment annotations", func() {
+		wp.Spec.DeploymentAnnotations = map[string]string{
+			"argocd.argoproj.io/sync-wave":      "1",
+			"deployment.kubernetes.io/revision": "1",
+		}
+		obj.Annotations = map[string]string{"deployment.kubernetes.io/revision": "3"}
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Annotations).To(Equal(map[string]string{
+			"argocd.argoproj.io/sync-wave":                "1",
+			"deployment.kubernetes.io/revision":           "3",
+			"wordpress.presslabs.org/applied-annotations": "argocd.argoproj.io/sync-wave",
+		}))
+
+		wp.Spec.DeploymentAnnotations = nil
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Annotations).To(Equal(map[string]string{"deployment.kubernetes.io/revision": "3"}))
+	})
+
+	It("should set the pod tolerations from the template", func() {
+		seconds := int64(30)
+		wp.Spec.DefaultTolerationSeconds = &seconds
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Template.Spec.Tolerations).To(Equal(wp.WebPodTemplateSpec().Spec.Tolerations))
+		Expect(obj.Spec.Template.Spec.Tolerations).To(HaveLen(2))
+	})
+
+	It("should drop the architecture node affinity once unset", func() {
+		wp.Spec.Architecture = "arm64"
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Template.Spec.Affinity).ToNot(BeNil())
+
+		wp.Spec.Architecture = ""
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Template.Spec.Affinity).To(BeNil())
+	})
+
+	It("should pause the deployment during the maintenance window", func() {
+		wp.Spec.MaintenanceWindow = &wordpressv1alpha1.MaintenanceWindowSpec{
+			Start: "11:00",
+			End:   "13:00",
+		}
+
+		// a deployment yet to be created is not paused
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Paused).To(BeFalse())
+
+		obj.CreationTimestamp = metav1.NewTime(now)
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Paused).To(BeTrue())
+		Expect(obj.Spec.Template.Spec.Containers).ToNot(BeEmpty())
+
+		wp.Spec.MaintenanceWindow = &wordpressv1alpha1.MaintenanceWindowSpec{
+			Start: "13:00",
+			End:   "14:00",
+		}
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Paused).To(BeFalse())
+	})
+
+	It("should roll out the pods on referenced secret changes when configured", func() {
+		wp.Spec.EnvFrom = []corev1.EnvFromSource{
+			{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}}},
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Data:       map[string][]byte{"DB_PASSWORD": []byte("old")},
+		}
+		c := fake.NewClientBuilder().WithObjects(secret).Build()
+		s = NewDeploymentSyncer(wp, &corev1.Secret{}, c, now).(*syncer.ObjectSyncer)
+		obj = s.Obj.(*appsv1.Deployment)
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Template.Annotations).ToNot(HaveKey("wordpress.presslabs.org/referencedSecretsHash"))
+
+		wp.Spec.RestartOnSecretChange = true
+		Expect(s.SyncFn()).To(Succeed())
+		hash := obj.Spec.Template.Annotations["wordpress.presslabs.org/referencedSecretsHash"]
+		Expect(hash).ToNot(BeEmpty())
+
+		secret.Data["DB_PASSWORD"] = []byte("new")
+		Expect(c.Update(context.TODO(), secret)).To(Succeed())
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Template.Annotations["wordpress.presslabs.org/referencedSecretsHash"]).ToNot(Equal(hash))
+	})
+
+	It("should remove the liveness probe when disabled", func() {
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Template.Spec.Containers[0].LivenessProbe).ToNot(BeNil())
+
+		wp.Spec.DisableLivenessProbe = true
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Spec.Template.Spec.Containers[0].LivenessProbe).To(BeNil())
+		Expect(obj.Spec.Template.Spec.Containers[0].ReadinessProbe).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/presslabs/controller-util/syncer"
+
+	wordpressv1alpha1 "github.com/bitpoke/wordpress-operator/pkg/apis/wordpress/v1alpha1"
+	"github.com/bitpoke/wordpress-operator/pkg/internal/wordpress"
+)
+
+var _ = Describe("The media PVC syncer", func() {
+	var wp *wordpress.Wordpress
+
+	BeforeEach(func() {
+		wp = wordpress.New(&wordpressv1alpha1.Wordpress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: wordpressv1alpha1.WordpressSpec{
+				MediaVolumeSpec: &wordpressv1alpha1.MediaVolumeSpec{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimSpec{},
+				},
+			},
+		})
+	})
+
+	It("should merge the annotations without overwriting the reserved ones", func() {
+		wp.Spec.MediaVolumeSpec.Annotations = map[string]string{
+			"snapshots.example.com/schedule":   "daily",
+			"pv.kubernetes.io/bind-completed":  "no",
+			"volume.kubernetes.io/storage-foo": "bar",
+		}
+
+		s := NewMediaPVCSyncer(wp, nil).(*syncer.ObjectSyncer)
+		obj := s.Obj.(*corev1.PersistentVolumeClaim)
+		obj.Annotations = map[string]string{
+			"pv.kubernetes.io/bind-completed": "yes",
+			"snapshots.example.com/schedule":  "hourly",
+		}
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Annotations).To(Equal(map[string]string{
+			"pv.kubernetes.io/bind-completed":             "yes",
+			"snapshots.example.com/schedule":              "daily",
+			"volume.kubernetes.io/storage-foo":            "bar",
+			"wordpress.presslabs.org/applied-annotations": "snapshots.example.com/schedule,volume.kubernetes.io/storage-foo",
+		}))
+
+		// reserved annotations may have been taken over, so they are kept
+		wp.Spec.MediaVolumeSpec.Annotations = nil
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Annotations).To(Equal(map[string]string{
+			"pv.kubernetes.io/bind-completed":  "yes",
+			"volume.kubernetes.io/storage-foo": "bar",
+		}))
+	})
+})
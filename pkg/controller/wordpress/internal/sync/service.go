@@ -46,13 +46,16 @@ func NewServiceSyncer(wp *wordpress.Wordpress, c client.Client) syncer.Interface
 	return syncer.NewObjectSyncer("Service", wp.Unwrap(), obj, c, func() error {
 		obj.Labels = labels.Merge(labels.Merge(obj.Labels, objLabels), controllerLabels)
 
-		selector := wp.WebPodLabels()
-		if !labels.Equals(selector, obj.Spec.Selector) {
-			if obj.ObjectMeta.CreationTimestamp.IsZero() {
-				obj.Spec.Selector = selector
-			} else {
-				return errImmutableServiceSelector
-			}
+		// the service annotations are mostly in the kubernetes.io domain (eg.
+		// for load balancers), so they overwrite the current ones
+		obj.ObjectMeta.Annotations = applyAnnotations(obj.ObjectMeta.Annotations, wp.Spec.ServiceAnnotations,
+			func(string) bool { return false })
+
+		// existing services keep their selector, as long as it matches the web pods
+		if obj.ObjectMeta.CreationTimestamp.IsZero() {
+			obj.Spec.Selector = wp.WebPodSelectorLabels()
+		} else if !labels.SelectorFromSet(obj.Spec.Selector).Matches(wp.WebPodLabels()) {
+			return errImmutableServiceSelector
 		}
 
 		if len(obj.Spec.Ports) != 2 {
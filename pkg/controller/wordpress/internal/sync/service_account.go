@@ -0,0 +1,60 @@
+/*
+Copyright 2018 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/presslabs/controller-util/syncer"
+
+	"github.com/bitpoke/wordpress-operator/pkg/internal/wordpress"
+)
+
+// NewServiceAccountSyncer returns a new sync.Interface for reconciling the
+// image pull secrets of the site's service account.
+func NewServiceAccountSyncer(wp *wordpress.Wordpress, c client.Client) syncer.Interface {
+	obj := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      wp.Spec.ServiceAccountName,
+			Namespace: wp.Namespace,
+		},
+	}
+
+	// the service account is not owned by the site, since it may be shared
+	// between sites or be managed by the user
+	return syncer.NewObjectSyncer("ServiceAccount", nil, obj, c, func() error {
+		for _, secret := range wp.Spec.ImagePullSecrets {
+			if !hasLocalObjectReference(obj.ImagePullSecrets, secret) {
+				obj.ImagePullSecrets = append(obj.ImagePullSecrets, secret)
+			}
+		}
+
+		return nil
+	})
+}
+
+func hasLocalObjectReference(refs []corev1.LocalObjectReference, ref corev1.LocalObjectReference) bool {
+	for i := range refs {
+		if refs[i].Name == ref.Name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/presslabs/controller-util/syncer"
+
+	wordpressv1alpha1 "github.com/bitpoke/wordpress-operator/pkg/apis/wordpress/v1alpha1"
+	"github.com/bitpoke/wordpress-operator/pkg/internal/wordpress"
+)
+
+var _ = Describe("The service syncer", func() {
+	var wp *wordpress.Wordpress
+
+	BeforeEach(func() {
+		wp = wordpress.New(&wordpressv1alpha1.Wordpress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		})
+	})
+
+	It("should set the service annotations", func() {
+		wp.Spec.ServiceAnnotations = map[string]string{
+			"service.beta.kubernetes.io/aws-load-balancer-internal": "true",
+		}
+
+		s := NewServiceSyncer(wp, nil).(*syncer.ObjectSyncer)
+		obj := s.Obj.(*corev1.Service)
+		obj.Annotations = map[string]string{"existing": "annotation"}
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Annotations).To(Equal(map[string]string{
+			"existing": "annotation",
+			"service.beta.kubernetes.io/aws-load-balancer-internal": "true",
+			"wordpress.presslabs.org/applied-annotations":           "service.beta.kubernetes.io/aws-load-balancer-internal",
+		}))
+
+		wp.Spec.ServiceAnnotations = map[string]string{
+			"service.beta.kubernetes.io/aws-load-balancer-type": "nlb",
+		}
+
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.Annotations).To(Equal(map[string]string{
+			"existing": "annotation",
+			"service.beta.kubernetes.io/aws-load-balancer-type": "nlb",
+			"wordpress.presslabs.org/applied-annotations":       "service.beta.kubernetes.io/aws-load-balancer-type",
+		}))
+	})
+})
@@ -0,0 +1,80 @@
+/*
+Copyright 2018 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/presslabs/controller-util/syncer"
+
+	"github.com/bitpoke/wordpress-operator/pkg/internal/wordpress"
+)
+
+var errImmutableStatefulSetSelector = errors.New("statefulset selector is immutable")
+
+// NewStatefulSetSyncer returns a new sync.Interface for reconciling web StatefulSet.
+func NewStatefulSetSyncer(wp *wordpress.Wordpress, secret *corev1.Secret, c client.Client) syncer.Interface {
+	objLabels := wp.ComponentLabels(wordpress.WordpressStatefulSet)
+
+	obj := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      wp.ComponentName(wordpress.WordpressStatefulSet),
+			Namespace: wp.Namespace,
+		},
+	}
+
+	return syncer.NewObjectSyncer("StatefulSet", wp.Unwrap(), obj, c, func() error {
+		obj.Labels = labels.Merge(labels.Merge(obj.Labels, objLabels), controllerLabels)
+		obj.Annotations = mergeAnnotations(obj.Annotations, wp.Spec.DeploymentAnnotations)
+
+		// statefulsets can't be paused, so a paused site only scales it
+		if wp.Spec.Paused && !obj.ObjectMeta.CreationTimestamp.IsZero() {
+			obj.Spec.Replicas = wp.Spec.Replicas
+
+			return nil
+		}
+
+		// the service name and the claim templates are immutable
+		if obj.ObjectMeta.CreationTimestamp.IsZero() {
+			obj.Spec.ServiceName = wp.ComponentName(wordpress.WordpressService)
+			obj.Spec.VolumeClaimTemplates = wp.MediaVolumeClaimTemplates()
+		}
+
+		err := syncPodTemplate(&obj.Spec.Template, wp, wp.StatefulSetPodTemplateSpec(), secret, c)
+		if err != nil {
+			return err
+		}
+
+		selector, ok := syncSelector(obj, obj.Spec.Selector, wp.WebPodSelector(), obj.Spec.Template.Labels, wordpress.SelectorChanged)
+		if !ok {
+			return errImmutableStatefulSetSelector
+		}
+		obj.Spec.Selector = selector
+
+		if wp.Spec.Replicas != nil {
+			obj.Spec.Replicas = wp.Spec.Replicas
+		}
+
+		return nil
+	})
+}
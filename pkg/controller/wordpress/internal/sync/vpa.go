@@ -0,0 +1,66 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/presslabs/controller-util/syncer"
+
+	"github.com/bitpoke/wordpress-operator/pkg/internal/wordpress"
+)
+
+// VPAGroupVersionKind is the GroupVersionKind of VerticalPodAutoscaler objects.
+var VPAGroupVersionKind = schema.GroupVersionKind{
+	Group:   "autoscaling.k8s.io",
+	Version: "v1",
+	Kind:    "VerticalPodAutoscaler",
+}
+
+// NewVPASyncer returns a new sync.Interface for reconciling the web
+// VerticalPodAutoscaler. It requires .spec.vpa to be set.
+func NewVPASyncer(wp *wordpress.Wordpress, c client.Client) syncer.Interface {
+	objLabels := wp.ComponentLabels(wordpress.WordpressVPA)
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(VPAGroupVersionKind)
+	obj.SetName(wp.ComponentName(wordpress.WordpressVPA))
+	obj.SetNamespace(wp.Namespace)
+
+	return syncer.NewObjectSyncer("VPA", wp.Unwrap(), obj, c, func() error {
+		obj.SetLabels(labels.Merge(labels.Merge(obj.GetLabels(), objLabels), controllerLabels))
+
+		targetKind, target := "Deployment", wordpress.WordpressDeployment
+		if wp.Spec.StatefulSetMode {
+			targetKind, target = "StatefulSet", wordpress.WordpressStatefulSet
+		}
+
+		targetRef := map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       targetKind,
+			"name":       wp.ComponentName(target),
+		}
+		if err := unstructured.SetNestedMap(obj.Object, targetRef, "spec", "targetRef"); err != nil {
+			return err
+		}
+
+		return unstructured.SetNestedField(obj.Object, wp.Spec.VPA.UpdateMode, "spec", "updatePolicy", "updateMode")
+	})
+}
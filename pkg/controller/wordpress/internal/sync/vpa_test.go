@@ -0,0 +1,78 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/presslabs/controller-util/syncer"
+
+	wordpressv1alpha1 "github.com/bitpoke/wordpress-operator/pkg/apis/wordpress/v1alpha1"
+	"github.com/bitpoke/wordpress-operator/pkg/internal/wordpress"
+)
+
+var _ = Describe("The VPA syncer", func() {
+	var (
+		wp  *wordpress.Wordpress
+		s   *syncer.ObjectSyncer
+		obj *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		wp = wordpress.New(&wordpressv1alpha1.Wordpress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: wordpressv1alpha1.WordpressSpec{
+				VPA: &wordpressv1alpha1.VPASpec{Enabled: true},
+			},
+		})
+		wp.SetDefaults()
+
+		s = NewVPASyncer(wp, nil).(*syncer.ObjectSyncer)
+		obj = s.Obj.(*unstructured.Unstructured)
+	})
+
+	It("should only compute recommendations by default", func() {
+		Expect(s.SyncFn()).To(Succeed())
+		Expect(obj.GroupVersionKind()).To(Equal(VPAGroupVersionKind))
+
+		mode, _, _ := unstructured.NestedString(obj.Object, "spec", "updatePolicy", "updateMode")
+		Expect(mode).To(Equal("Off"))
+
+		kind, _, _ := unstructured.NestedString(obj.Object, "spec", "targetRef", "kind")
+		Expect(kind).To(Equal("Deployment"))
+		name, _, _ := unstructured.NestedString(obj.Object, "spec", "targetRef", "name")
+		Expect(name).To(Equal(wp.ComponentName(wordpress.WordpressDeployment)))
+	})
+
+	It("should target the statefulset in statefulset mode", func() {
+		wp.Spec.StatefulSetMode = true
+		wp.Spec.VPA.UpdateMode = "Auto"
+
+		Expect(s.SyncFn()).To(Succeed())
+
+		kind, _, _ := unstructured.NestedString(obj.Object, "spec", "targetRef", "kind")
+		Expect(kind).To(Equal("StatefulSet"))
+		name, _, _ := unstructured.NestedString(obj.Object, "spec", "targetRef", "name")
+		Expect(name).To(Equal(wp.ComponentName(wordpress.WordpressStatefulSet)))
+		mode, _, _ := unstructured.NestedString(obj.Object, "spec", "updatePolicy", "updateMode")
+		Expect(mode).To(Equal("Auto"))
+	})
+})
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wordpress
+
+import (
+	"time"
+
+	"github.com/presslabs/controller-util/syncer"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	wordpressv1alpha1 "github.com/bitpoke/wordpress-operator/pkg/apis/wordpress/v1alpha1"
+	"github.com/bitpoke/wordpress-operator/pkg/controller/wordpress/internal/sync"
+	"github.com/bitpoke/wordpress-operator/pkg/internal/wordpress"
+)
+
+// RenderWebPodTemplate renders the web pod template of a Wordpress site as a
+// PodTemplate YAML document, without talking to the cluster. Map keys are
+// sorted, so the output is stable for a given site.
+func RenderWebPodTemplate(wp *wordpressv1alpha1.Wordpress) ([]byte, error) {
+	w := wordpress.New(wp.DeepCopy())
+	w.SetDefaults()
+
+	tpl := &corev1.PodTemplate{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "PodTemplate",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      w.ComponentName(wordpress.WordpressDeployment),
+			Namespace: w.Namespace,
+		},
+		Template: w.WebPodTemplateSpec(),
+	}
+
+	return yaml.Marshal(tpl)
+}
+
+// RenderDeployment renders the web Deployment of a Wordpress site as YAML, as
+// it would be created by the operator, without talking to the cluster.
+func RenderDeployment(wp *wordpressv1alpha1.Wordpress) ([]byte, error) {
+	w := wordpress.New(wp.DeepCopy())
+	w.SetDefaults()
+
+	s := sync.NewDeploymentSyncer(w, &corev1.Secret{}, nil, time.Now()).(*syncer.ObjectSyncer)
+	if err := s.SyncFn(); err != nil {
+		return nil, err
+	}
+
+	deploy := s.Obj.(*appsv1.Deployment)
+	deploy.TypeMeta = metav1.TypeMeta{
+		APIVersion: appsv1.SchemeGroupVersion.String(),
+		Kind:       "Deployment",
+	}
+
+	return yaml.Marshal(deploy)
+}
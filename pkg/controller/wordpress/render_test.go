@@ -0,0 +1,71 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wordpress
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	wordpressv1alpha1 "github.com/bitpoke/wordpress-operator/pkg/apis/wordpress/v1alpha1"
+)
+
+var _ = Describe("Rendering", func() {
+	var wp *wordpressv1alpha1.Wordpress
+
+	BeforeEach(func() {
+		wp = &wordpressv1alpha1.Wordpress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "render",
+				Namespace: "default",
+			},
+			Spec: wordpressv1alpha1.WordpressSpec{
+				Routes: []wordpressv1alpha1.RouteSpec{{Domain: "example.com"}},
+			},
+		}
+	})
+
+	It("renders the web pod template", func() {
+		out, err := RenderWebPodTemplate(wp)
+		Expect(err).ToNot(HaveOccurred())
+
+		tpl := &corev1.PodTemplate{}
+		Expect(yaml.Unmarshal(out, tpl)).To(Succeed())
+		Expect(tpl.Kind).To(Equal("PodTemplate"))
+		Expect(tpl.Template.Spec.Containers[0].Name).To(Equal("wordpress"))
+		Expect(wp.Spec.Image).To(BeEmpty())
+	})
+
+	It("renders the web deployment deterministically", func() {
+		out, err := RenderDeployment(wp)
+		Expect(err).ToNot(HaveOccurred())
+
+		again, err := RenderDeployment(wp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(again).To(Equal(out))
+
+		deploy := &appsv1.Deployment{}
+		Expect(yaml.Unmarshal(out, deploy)).To(Succeed())
+		Expect(deploy.Kind).To(Equal("Deployment"))
+		Expect(deploy.Name).To(Equal("render"))
+		Expect(deploy.Spec.Selector.MatchLabels).To(HaveKeyWithValue("app.kubernetes.io/component", "web"))
+	})
+})
@@ -18,6 +18,7 @@ package wordpress
 
 import (
 	"context"
+	"time"
 
 	"github.com/presslabs/controller-util/syncer"
 	appsv1 "k8s.io/api/apps/v1"
@@ -26,6 +27,7 @@ import (
 	netv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
@@ -43,6 +45,13 @@ import (
 
 const controllerName = "wordpress-controller"
 
+// rolloutSecretsField indexes the sites by the secrets rolling out their pods.
+const rolloutSecretsField = "spec.rolloutSecrets"
+
+// orphanedByLabel marks the replica sets orphaned by a recreated web
+// deployment with the site name, for them to be removed later on.
+const orphanedByLabel = "wordpress.presslabs.org/orphaned-by"
+
 // Add creates a new Wordpress Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
@@ -70,6 +79,7 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 
 	subresources := []client.Object{
 		&appsv1.Deployment{},
+		&appsv1.StatefulSet{},
 		&corev1.PersistentVolumeClaim{},
 		&corev1.Service{},
 		&corev1.Secret{},
@@ -86,9 +96,48 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		}
 	}
 
+	// roll out the sites referencing changed secrets, if requested. The sites
+	// are looked up by an index holding only the opted in ones.
+	err = mgr.GetFieldIndexer().IndexField(context.TODO(), &wordpressv1alpha1.Wordpress{}, rolloutSecretsField,
+		func(obj client.Object) []string {
+			return wordpress.New(obj.(*wordpressv1alpha1.Wordpress)).RolloutSecretNames()
+		})
+	if err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		return sitesReferencingSecret(mgr.GetClient(), obj)
+	}))
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// sitesReferencingSecret returns the requests for the sites which roll out
+// their pods on changes to the given secret.
+func sitesReferencingSecret(c client.Client, secret client.Object) []reconcile.Request {
+	sites := &wordpressv1alpha1.WordpressList{}
+
+	err := c.List(context.TODO(), sites, client.InNamespace(secret.GetNamespace()),
+		client.MatchingFields{rolloutSecretsField: secret.GetName()})
+	if err != nil {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+
+	for i := range sites.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: sites.Items[i].Name, Namespace: sites.Items[i].Namespace},
+		})
+	}
+
+	return requests
+}
+
 var _ reconcile.Reconciler = &ReconcileWordpress{}
 
 // ReconcileWordpress reconciles a Wordpress object.
@@ -99,10 +148,11 @@ type ReconcileWordpress struct {
 }
 
 // Automatically generate RBAC rules to allow the Controller to read and write Deployments
-// +kubebuilder:rbac:groups=core,resources=secrets;services;persistentvolumeclaims;events,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets;services;serviceaccounts;persistentvolumeclaims;events,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments;replicasets;statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=autoscaling.k8s.io,resources=verticalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=wordpress.presslabs.org,resources=wordpresses;wordpresses/status,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile reads that state of the cluster for a Wordpress object and makes changes based on the state read
@@ -125,8 +175,69 @@ func (r *ReconcileWordpress) Reconcile(ctx context.Context, request reconcile.Re
 	r.scheme.Default(wp.Unwrap())
 	wp.SetDefaults()
 
+	if err = wp.ValidateFiles(); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err = wp.ValidateRoutes(); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err = wp.ValidateImage(); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err = wp.ValidateCodeVolume(); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err = wp.ValidateReplicas(); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err = wp.ValidateSharedSocketVolume(); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err = wp.ValidateSharedSessionVolume(); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err = wp.ValidateSecurityProfile(); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err = wp.ValidateWarmup(); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err = wp.ValidateProbeDefaults(); err != nil {
+		r.recorder.Event(wp.Unwrap(), corev1.EventTypeWarning, "InvalidProbeDefaults", err.Error())
+
+		return reconcile.Result{}, err
+	}
+
+	if err = r.validateMediaVolume(ctx, wp); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	now := time.Now()
+
+	// while paused, only the web workload is kept in sync, in order to pause it
+	if wp.Spec.Paused {
+		err = r.sync(ctx, []syncer.Interface{newWebSyncer(wp, &corev1.Secret{}, r.Client, now)})
+
+		return reconcile.Result{}, err
+	}
+
+	// a web deployment with a stale selector gets recreated, keeping its pods
+	var recreating bool
+	if recreating, err = r.recreateWebDeployment(ctx, wp); err != nil || recreating {
+		return reconcile.Result{}, err
+	}
+
 	secretSyncer := sync.NewSecretSyncer(wp, r.Client)
-	deploySyncer := sync.NewDeploymentSyncer(wp, secretSyncer.Object().(*corev1.Secret), r.Client)
+	deploySyncer := newWebSyncer(wp, secretSyncer.Object().(*corev1.Secret), r.Client, now)
 	syncers := []syncer.Interface{
 		secretSyncer,
 		deploySyncer,
@@ -135,20 +246,39 @@ func (r *ReconcileWordpress) Reconcile(ctx context.Context, request reconcile.Re
 		// sync.NewDBUpgradeJobSyncer(wp, r.Client),
 	}
 
+	if wp.Spec.Canary != nil {
+		syncers = append(syncers, sync.NewCanaryDeploymentSyncer(wp, secretSyncer.Object().(*corev1.Secret), r.Client))
+	}
+
+	if wp.Spec.VPA != nil && wp.Spec.VPA.Enabled && r.hasVPASupport() {
+		syncers = append(syncers, sync.NewVPASyncer(wp, r.Client))
+	}
+
 	if wp.Spec.CodeVolumeSpec != nil && wp.Spec.CodeVolumeSpec.PersistentVolumeClaim != nil {
 		syncers = append(syncers, sync.NewCodePVCSyncer(wp, r.Client))
 	}
 
-	if wp.Spec.MediaVolumeSpec != nil && wp.Spec.MediaVolumeSpec.PersistentVolumeClaim != nil {
+	// in StatefulSet mode, each replica gets its own media claim
+	if wp.Spec.MediaVolumeSpec != nil && wp.Spec.MediaVolumeSpec.PersistentVolumeClaim != nil && !wp.Spec.StatefulSetMode {
 		syncers = append(syncers, sync.NewMediaPVCSyncer(wp, r.Client))
 	}
 
+	// the service account needs its pull secrets before pods get created
+	if wp.ManagesServiceAccount() {
+		syncers = append([]syncer.Interface{sync.NewServiceAccountSyncer(wp, r.Client)}, syncers...)
+	}
+
 	if err = r.sync(ctx, syncers); err != nil {
 		return reconcile.Result{}, err
 	}
 
 	oldStatus := wp.Status.DeepCopy()
-	wp.Status.Replicas = deploySyncer.Object().(*appsv1.Deployment).Status.Replicas
+	switch obj := deploySyncer.Object().(type) {
+	case *appsv1.Deployment:
+		wp.Status.Replicas = obj.Status.Replicas
+	case *appsv1.StatefulSet:
+		wp.Status.Replicas = obj.Status.Replicas
+	}
 
 	if oldStatus.Replicas != wp.Status.Replicas {
 		if errUp := r.Status().Update(ctx, wp.Unwrap()); errUp != nil {
@@ -161,9 +291,84 @@ func (r *ReconcileWordpress) Reconcile(ctx context.Context, request reconcile.Re
 		return reconcile.Result{}, err
 	}
 
+	// remove the canary deployment if canary is no longer set
+	if err = r.cleanupCanaryDeployment(ctx, wp); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// remove the vertical pod autoscaler if it's no longer enabled
+	if err = r.cleanupVPA(ctx, wp); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// remove the web workload of the kind no longer in use
+	if err = r.cleanupWebWorkload(ctx, wp); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// remove the replica sets orphaned by a recreated web deployment
+	if err = r.cleanupOrphanedReplicaSets(ctx, wp); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// pause or resume the web deployment when the maintenance window starts or ends
+	if _, next := wp.InMaintenanceWindow(now); next > 0 {
+		return reconcile.Result{RequeueAfter: next}, nil
+	}
+
 	return reconcile.Result{}, nil
 }
 
+// validateMediaVolume validates the media volume spec and reports the
+// result as the MediaVolumeValid condition.
+func (r *ReconcileWordpress) validateMediaVolume(ctx context.Context, wp *wordpress.Wordpress) error {
+	e := wp.ValidateMediaVolume()
+
+	idx := -1
+
+	for i := range wp.Status.Conditions {
+		if wp.Status.Conditions[i].Type == wordpressv1alpha1.MediaVolumeValidCondition {
+			idx = i
+		}
+	}
+
+	if idx == -1 {
+		if e == nil {
+			return nil
+		}
+
+		wp.Status.Conditions = append(wp.Status.Conditions, wordpressv1alpha1.WordpressCondition{
+			Type: wordpressv1alpha1.MediaVolumeValidCondition,
+		})
+		idx = len(wp.Status.Conditions) - 1
+	}
+
+	status, reason, message := corev1.ConditionTrue, wordpressv1alpha1.MediaVolumeValidReason, "the media volume sources are valid"
+	if e != nil {
+		status, reason, message = corev1.ConditionFalse, wordpressv1alpha1.MediaVolumeInvalidReason, e.Error()
+	}
+
+	cond := &wp.Status.Conditions[idx]
+	if cond.Status == status && cond.Message == message {
+		return e
+	}
+
+	now := metav1.Now()
+	cond.LastUpdateTime = now
+
+	if cond.Status != status {
+		cond.LastTransitionTime = now
+	}
+
+	cond.Status, cond.Reason, cond.Message = status, reason, message
+
+	if err := r.Status().Update(ctx, wp.Unwrap()); err != nil {
+		return err
+	}
+
+	return e
+}
+
 func ignoreNotFound(err error) error {
 	if errors.IsNotFound(err) {
 		return nil
@@ -221,6 +426,224 @@ func (r *ReconcileWordpress) cleanupCronJob(ctx context.Context, wp *wordpress.W
 	return r.Delete(ctx, cronJob)
 }
 
+func (r *ReconcileWordpress) cleanupCanaryDeployment(ctx context.Context, wp *wordpress.Wordpress) error {
+	if wp.Spec.Canary != nil {
+		return nil
+	}
+
+	key := types.NamespacedName{
+		Name:      wp.ComponentName(wordpress.WordpressCanaryDeployment),
+		Namespace: wp.Namespace,
+	}
+
+	deploy := &appsv1.Deployment{}
+
+	if err := r.Get(ctx, key, deploy); err != nil {
+		return ignoreNotFound(err)
+	}
+
+	if !isOwnedBy(deploy.OwnerReferences, wp) {
+		return nil
+	}
+
+	return r.Delete(ctx, deploy)
+}
+
+// cleanupVPA removes the VerticalPodAutoscaler once .spec.vpa is disabled or
+// unset, since in the Recreate and Auto modes it would keep evicting pods.
+func (r *ReconcileWordpress) cleanupVPA(ctx context.Context, wp *wordpress.Wordpress) error {
+	if (wp.Spec.VPA != nil && wp.Spec.VPA.Enabled) || !r.hasVPASupport() {
+		return nil
+	}
+
+	key := types.NamespacedName{
+		Name:      wp.ComponentName(wordpress.WordpressVPA),
+		Namespace: wp.Namespace,
+	}
+
+	vpa := &unstructured.Unstructured{}
+	vpa.SetGroupVersionKind(sync.VPAGroupVersionKind)
+
+	if err := r.Get(ctx, key, vpa); err != nil {
+		return ignoreNotFound(err)
+	}
+
+	if !isOwnedBy(vpa.GetOwnerReferences(), wp) {
+		return nil
+	}
+
+	return r.Delete(ctx, vpa)
+}
+
+// recreateWebDeployment deletes the web Deployment, orphaning its pods, when
+// its immutable selector no longer selects the web pods (eg. after a labels
+// change), so it gets recreated with the current selector. The orphaned
+// replica sets are labeled for cleanupOrphanedReplicaSets. It reports
+// whether the Deployment is being deleted, in which case syncing waits for
+// the deletion to complete.
+func (r *ReconcileWordpress) recreateWebDeployment(ctx context.Context, wp *wordpress.Wordpress) (bool, error) {
+	if wp.Spec.StatefulSetMode {
+		return false, nil
+	}
+
+	key := types.NamespacedName{
+		Name:      wp.ComponentName(wordpress.WordpressDeployment),
+		Namespace: wp.Namespace,
+	}
+
+	deploy := &appsv1.Deployment{}
+
+	if err := r.Get(ctx, key, deploy); err != nil {
+		return false, ignoreNotFound(err)
+	}
+
+	if !isOwnedBy(deploy.OwnerReferences, wp) {
+		return false, nil
+	}
+
+	if !deploy.DeletionTimestamp.IsZero() {
+		return true, nil
+	}
+
+	if !wp.WebSelectorChanged(deploy.Spec.Selector, wp.WebPodTemplateSpec().Labels) {
+		return false, nil
+	}
+
+	if err := r.labelOrphanedReplicaSets(ctx, wp, deploy); err != nil {
+		return false, err
+	}
+
+	r.recorder.Event(wp.Unwrap(), corev1.EventTypeNormal, "RecreatingDeployment",
+		"recreating the web deployment, as its selector no longer matches the pods")
+
+	return true, r.Delete(ctx, deploy, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+}
+
+// labelOrphanedReplicaSets labels the replica sets of the given Deployment,
+// before it gets deleted orphaning them.
+func (r *ReconcileWordpress) labelOrphanedReplicaSets(ctx context.Context, wp *wordpress.Wordpress,
+	deploy *appsv1.Deployment) error {
+	rsList := &appsv1.ReplicaSetList{}
+	if err := r.List(ctx, rsList, client.InNamespace(deploy.Namespace)); err != nil {
+		return err
+	}
+
+	for i := range rsList.Items {
+		rs := &rsList.Items[i]
+		if !metav1.IsControlledBy(rs, deploy) || rs.Labels[orphanedByLabel] == wp.Name {
+			continue
+		}
+
+		patch := client.MergeFrom(rs.DeepCopy())
+
+		if rs.Labels == nil {
+			rs.Labels = map[string]string{}
+		}
+		rs.Labels[orphanedByLabel] = wp.Name
+
+		if err := r.Patch(ctx, rs, patch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanupOrphanedReplicaSets deletes, along with their pods, the replica sets
+// orphaned by recreateWebDeployment once the new web Deployment is rolled
+// out. The ones adopted by the new Deployment are left to it.
+func (r *ReconcileWordpress) cleanupOrphanedReplicaSets(ctx context.Context, wp *wordpress.Wordpress) error {
+	if wp.Spec.StatefulSetMode {
+		return nil
+	}
+
+	rsList := &appsv1.ReplicaSetList{}
+
+	err := r.List(ctx, rsList, client.InNamespace(wp.Namespace), client.MatchingLabels{orphanedByLabel: wp.Name})
+	if err != nil || len(rsList.Items) == 0 {
+		return err
+	}
+
+	key := types.NamespacedName{
+		Name:      wp.ComponentName(wordpress.WordpressDeployment),
+		Namespace: wp.Namespace,
+	}
+
+	deploy := &appsv1.Deployment{}
+
+	if err = r.Get(ctx, key, deploy); err != nil {
+		return ignoreNotFound(err)
+	}
+
+	if !isOwnedBy(deploy.OwnerReferences, wp) || !deploy.DeletionTimestamp.IsZero() || !deploymentRolledOut(deploy) {
+		return nil
+	}
+
+	for i := range rsList.Items {
+		if metav1.GetControllerOf(&rsList.Items[i]) != nil {
+			continue
+		}
+
+		if err = r.Delete(ctx, &rsList.Items[i]); ignoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deploymentRolledOut reports whether all the Deployment replicas are updated
+// and available.
+func deploymentRolledOut(deploy *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+
+	return deploy.Status.ObservedGeneration >= deploy.Generation &&
+		deploy.Status.UpdatedReplicas == replicas &&
+		deploy.Status.AvailableReplicas >= replicas
+}
+
+func (r *ReconcileWordpress) cleanupWebWorkload(ctx context.Context, wp *wordpress.Wordpress) error {
+	var obj client.Object = &appsv1.StatefulSet{}
+	if wp.Spec.StatefulSetMode {
+		obj = &appsv1.Deployment{}
+	}
+
+	key := types.NamespacedName{
+		Name:      wp.ComponentName(wordpress.WordpressDeployment),
+		Namespace: wp.Namespace,
+	}
+
+	if err := r.Get(ctx, key, obj); err != nil {
+		return ignoreNotFound(err)
+	}
+
+	if !isOwnedBy(obj.GetOwnerReferences(), wp) {
+		return nil
+	}
+
+	return r.Delete(ctx, obj)
+}
+
+// newWebSyncer returns the syncer for the web pods workload, which is a
+// StatefulSet in StatefulSet mode and a Deployment otherwise.
+func newWebSyncer(wp *wordpress.Wordpress, secret *corev1.Secret, c client.Client, now time.Time) syncer.Interface {
+	if wp.Spec.StatefulSetMode {
+		return sync.NewStatefulSetSyncer(wp, secret, c)
+	}
+
+	return sync.NewDeploymentSyncer(wp, secret, c, now)
+}
+
+// hasVPASupport checks if the VerticalPodAutoscaler CRD is installed.
+func (r *ReconcileWordpress) hasVPASupport() bool {
+	_, err := r.RESTMapper().RESTMapping(sync.VPAGroupVersionKind.GroupKind(), sync.VPAGroupVersionKind.Version)
+
+	return err == nil
+}
+
 func isOwnedBy(refs []metav1.OwnerReference, owner *wordpress.Wordpress) bool {
 	for _, ref := range refs {
 		if (ref.Kind == "Wordpress" || ref.Kind == "wordpress") && ref.Name == owner.Name {
@@ -182,5 +182,193 @@ var _ = Describe("Wordpress controller", func() {
 			Expect(c.Get(context.TODO(), key, deploy)).To(Succeed())
 			Expect(deploy.Spec.Strategy.Type).To(Equal(appsv1.RecreateDeploymentStrategyType))
 		})
+
+		It("sets the deployment progress deadline", func() {
+			key := types.NamespacedName{
+				Name:      wp.Name,
+				Namespace: wp.Namespace,
+			}
+			deploy := &appsv1.Deployment{}
+			Eventually(func() error { return c.Get(context.TODO(), key, deploy) }, timeout).Should(Succeed())
+			Expect(*deploy.Spec.ProgressDeadlineSeconds).To(Equal(int32(600)))
+
+			progressDeadlineSeconds := int32(120)
+			wp.Spec.ProgressDeadlineSeconds = &progressDeadlineSeconds
+			Expect(c.Update(context.TODO(), wp)).To(Succeed())
+			Eventually(requests, timeout).Should(Receive(Equal(expectedRequest)))
+
+			Expect(c.Get(context.TODO(), key, deploy)).To(Succeed())
+			Expect(*deploy.Spec.ProgressDeadlineSeconds).To(Equal(progressDeadlineSeconds))
+		})
+
+		It("pauses the deployment and stops updating it", func() {
+			key := types.NamespacedName{
+				Name:      wp.Name,
+				Namespace: wp.Namespace,
+			}
+			deploy := &appsv1.Deployment{}
+			Eventually(func() error { return c.Get(context.TODO(), key, deploy) }, timeout).Should(Succeed())
+			Expect(deploy.Spec.Paused).To(BeFalse())
+
+			wp.Spec.Paused = true
+			wp.Spec.Image = "docker.io/bitpoke/wordpress-runtime:paused"
+			Expect(c.Update(context.TODO(), wp)).To(Succeed())
+			Eventually(requests, timeout).Should(Receive(Equal(expectedRequest)))
+
+			Expect(c.Get(context.TODO(), key, deploy)).To(Succeed())
+			Expect(deploy.Spec.Paused).To(BeTrue())
+			Expect(deploy.Spec.Template.Spec.Containers[0].Image).ToNot(Equal(wp.Spec.Image))
+		})
+
+		It("replaces the deployment with a statefulset in statefulset mode", func() {
+			key := types.NamespacedName{
+				Name:      wp.Name,
+				Namespace: wp.Namespace,
+			}
+			Eventually(func() error { return c.Get(context.TODO(), key, &appsv1.Deployment{}) }, timeout).Should(Succeed())
+
+			wp.Spec.StatefulSetMode = true
+			Expect(c.Update(context.TODO(), wp)).To(Succeed())
+			Eventually(requests, timeout).Should(Receive(Equal(expectedRequest)))
+
+			sts := &appsv1.StatefulSet{}
+			Eventually(func() error { return c.Get(context.TODO(), key, sts) }, timeout).Should(Succeed())
+			Expect(sts.Spec.VolumeClaimTemplates).To(HaveLen(1))
+			Expect(sts.Spec.VolumeClaimTemplates[0].Name).To(Equal("media"))
+
+			deploy := &appsv1.Deployment{}
+			Eventually(func() error { return c.Get(context.TODO(), key, deploy) }, timeout).ShouldNot(Succeed())
+
+			Expect(c.Delete(context.TODO(), sts)).To(Succeed())
+		})
+	})
+
+	When("the web deployment selector is stale", func() {
+		var (
+			wp     *wordpressv1alpha1.Wordpress
+			deploy *appsv1.Deployment
+			rs     *appsv1.ReplicaSet
+		)
+
+		BeforeEach(func() {
+			name := fmt.Sprintf("wp-%d", rand.Int31())
+			legacyLabels := map[string]string{
+				"app.kubernetes.io/name":      "wordpress",
+				"app.kubernetes.io/part-of":   "blog",
+				"app.kubernetes.io/instance":  name,
+				"app.kubernetes.io/component": "web",
+			}
+			template := corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: legacyLabels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "wordpress", Image: "wordpress"}},
+				},
+			}
+			controller := true
+
+			// a deployment created by an older operator version, selecting
+			// on labels the pods no longer have
+			deploy = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: wordpressv1alpha1.SchemeGroupVersion.String(),
+						Kind:       "Wordpress",
+						Name:       name,
+						UID:        "legacy",
+						Controller: &controller,
+					}},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: metav1.SetAsLabelSelector(legacyLabels),
+					Template: template,
+				},
+			}
+			Expect(c.Create(context.TODO(), deploy)).To(Succeed())
+
+			rs = &appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name + "-legacy",
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "apps/v1",
+						Kind:       "Deployment",
+						Name:       deploy.Name,
+						UID:        deploy.UID,
+						Controller: &controller,
+					}},
+				},
+				Spec: appsv1.ReplicaSetSpec{
+					Selector: metav1.SetAsLabelSelector(legacyLabels),
+					Template: template,
+				},
+			}
+			Expect(c.Create(context.TODO(), rs)).To(Succeed())
+
+			wp = &wordpressv1alpha1.Wordpress{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: wordpressv1alpha1.WordpressSpec{
+					Routes: []wordpressv1alpha1.RouteSpec{{Domain: fmt.Sprintf("%s.example.com", name)}},
+				},
+			}
+			Expect(c.Create(context.TODO(), wp)).To(Succeed())
+		})
+
+		// nolint: errcheck
+		AfterEach(func() {
+			c.Delete(context.TODO(), wp)
+			c.Delete(context.TODO(), rs)
+			c.Delete(context.TODO(), &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: wp.Name, Namespace: wp.Namespace}})
+		})
+
+		It("recreates the deployment and removes the orphaned replica sets once rolled out", func() {
+			key := types.NamespacedName{Name: wp.Name, Namespace: wp.Namespace}
+			rsKey := types.NamespacedName{Name: rs.Name, Namespace: rs.Namespace}
+
+			Eventually(func() map[string]string {
+				_ = c.Get(context.TODO(), rsKey, rs)
+
+				return rs.Labels
+			}, timeout).Should(HaveKeyWithValue(orphanedByLabel, wp.Name))
+
+			// there is no garbage collector to orphan the replica set and
+			// finish the deletion
+			Eventually(func() error {
+				_ = c.Get(context.TODO(), key, deploy)
+				if deploy.DeletionTimestamp.IsZero() {
+					return fmt.Errorf("deployment %s is not being deleted", key)
+				}
+
+				deploy.Finalizers = nil
+
+				return c.Update(context.TODO(), deploy)
+			}, timeout).Should(Succeed())
+
+			Expect(c.Get(context.TODO(), rsKey, rs)).To(Succeed())
+			rs.OwnerReferences = nil
+			Expect(c.Update(context.TODO(), rs)).To(Succeed())
+
+			Eventually(func() bool {
+				deploy = &appsv1.Deployment{}
+				if err := c.Get(context.TODO(), key, deploy); err != nil {
+					return false
+				}
+
+				return deploy.DeletionTimestamp.IsZero() && deploy.Spec.Selector.MatchLabels["app.kubernetes.io/part-of"] == ""
+			}, timeout).Should(BeTrue())
+
+			// the orphaned replica set is kept until the new deployment is rolled out
+			Consistently(func() error { return c.Get(context.TODO(), rsKey, &appsv1.ReplicaSet{}) }).Should(Succeed())
+
+			deploy.Status.ObservedGeneration = deploy.Generation
+			deploy.Status.Replicas = *deploy.Spec.Replicas
+			deploy.Status.UpdatedReplicas = *deploy.Spec.Replicas
+			deploy.Status.ReadyReplicas = *deploy.Spec.Replicas
+			deploy.Status.AvailableReplicas = *deploy.Spec.Replicas
+			Expect(c.Status().Update(context.TODO(), deploy)).To(Succeed())
+
+			Eventually(func() error { return c.Get(context.TODO(), rsKey, &appsv1.ReplicaSet{}) }, timeout).ShouldNot(Succeed())
+		})
 	})
 })
@@ -48,7 +48,10 @@ const (
 	cronTriggerTimeout  = 30 * time.Second
 )
 
-var errHTTP = errors.New("HTTP error")
+var (
+	errHTTP            = errors.New("HTTP error")
+	errWPCronSuspended = errors.New("wp-cron is suspended")
+)
 
 // Add creates a new Wordpress Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
@@ -112,6 +115,16 @@ func (r *ReconcileWordpress) Reconcile(ctx context.Context, request reconcile.Re
 
 	log := r.Log.WithValues("key", request.NamespacedName)
 
+	if wp.Spec.WPCron != nil && wp.Spec.WPCron.Suspend {
+		err = r.updateWPCronStatus(ctx, wp, errWPCronSuspended)
+		if err != nil {
+			log.Error(err, "error updating wordpress wp-cron status")
+		}
+
+		// changing the Wordpress resource enqueues it again
+		return reconcile.Result{}, nil
+	}
+
 	requeue := reconcile.Result{
 		Requeue:      true,
 		RequeueAfter: cronTriggerInterval,
@@ -149,6 +162,21 @@ func (r *ReconcileWordpress) Reconcile(ctx context.Context, request reconcile.Re
 func maybeUpdateWPCronCondition(cond wordpressv1alpha1.WordpressCondition, err error) (wordpressv1alpha1.WordpressCondition, bool) {
 	needsUpdate := false
 
+	if errors.Is(err, errWPCronSuspended) {
+		if cond.Reason != wordpressv1alpha1.WPCronSuspendedReason {
+			now := metav1.Now()
+			cond.LastUpdateTime = now
+			cond.LastTransitionTime = now
+			cond.Status = corev1.ConditionUnknown
+			cond.Reason = wordpressv1alpha1.WPCronSuspendedReason
+			cond.Message = err.Error()
+
+			return cond, true
+		}
+
+		return cond, false
+	}
+
 	// We've got an error, but WPCronTriggering is true/unknown/empty
 	if err != nil && (cond.Status == corev1.ConditionTrue || cond.Status == corev1.ConditionUnknown || cond.Status == "") {
 		needsUpdate = true
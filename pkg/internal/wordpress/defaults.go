@@ -22,18 +22,42 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 
+	wordpressv1alpha1 "github.com/bitpoke/wordpress-operator/pkg/apis/wordpress/v1alpha1"
 	"github.com/bitpoke/wordpress-operator/pkg/cmd/options"
 )
 
 const (
 	codeSrcMountPath = "/var/run/presslabs.org/code/src"
 
+	gitSSHKeyVolume    = "git-ssh-key"
+	gitSSHKeyMountPath = "/var/run/presslabs.org/git/ssh"
+	gitSSHKeyFileName  = "id_rsa"
+
+	gitCABundleVolume    = "git-ca-bundle"
+	gitCABundleMountPath = "/var/run/presslabs.org/git/ca"
+	gitCABundleFileName  = "ca.crt"
+
+	gitVerifyKeyVolume    = "git-verify-key"
+	gitVerifyKeyMountPath = "/var/run/presslabs.org/git/gpg"
+	gitVerifyKeyFileName  = "pubkeys.asc"
+
+	gitMirrorSubdir             = ".git-mirror"
+	defaultMirrorWorktreeSubdir = "worktree"
+
+	gitMediaMountPath = "/var/run/presslabs.org/media"
+
+	gitReferenceVolume    = "git-reference"
+	gitReferenceMountPath = "/var/run/presslabs.org/git/reference"
+
 	defaultCodeMountPath   = "/app/web/wp-content"
 	defaultRepoCodeSubPath = "wp-content"
 
 	configMountPath          = "/app/config"
 	defaultRepoConfigSubPath = "config"
 
+	configVolumeName   = "config"
+	configSrcMountPath = "/var/run/presslabs.org/config/src"
+
 	mediaSubPath          = "uploads"
 	defaultMediaMountPath = defaultCodeMountPath + "/" + mediaSubPath
 
@@ -42,9 +66,60 @@ const (
 
 	knativeInternalVolume    = "knative-internal"
 	knativeInternalMountPath = "/var/knative-internal"
+
+	podInfoVolume    = "podinfo"
+	podInfoMountPath = "/etc/podinfo"
+
+	jobOutputVolume    = "job-output"
+	jobOutputMountPath = "/var/run/job-output"
+
+	mediaTokenVolume    = "media-token"
+	mediaTokenMountPath = "/var/run/secrets/presslabs.org/media"
+	mediaTokenFileName  = "token"
+
+	sharedSocketVolume           = "shared-socket"
+	defaultSharedSocketMountPath = "/var/run/sockets"
+
+	sharedSessionVolume           = "shared-session"
+	defaultSharedSessionMountPath = "/var/lib/php/sessions"
+
+	vaultSecretsVolume           = "vault-secrets"
+	vaultAgentConfigVolume       = "vault-agent-config"
+	vaultAgentConfigMountPath    = "/vault/config"
+	defaultVaultAgentImage       = "docker.io/hashicorp/vault:1.8.4"
+	defaultVaultSecretsMountPath = "/vault/secrets"
+	defaultVaultSecretsFile      = "secrets"
+
+	defaultFPMExporterImage      = "docker.io/hipages/php-fpm_exporter:2.2.0"
+	defaultFPMExporterSocket     = "php-fpm.sock"
+	defaultFPMExporterStatusPath = "/status"
+
+	defaultDebugLogPath = "wp-debug.log"
+
+	mediaCacheVolume             = "media-cache"
+	defaultMediaPreSyncImage     = "docker.io/rclone/rclone:1.57.0"
+	defaultMediaPreSyncMountPath = "/var/cache/media"
+
+	defaultTerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+)
+
+var (
+	varLogSizeLimit        = resource.MustParse("1Gi")
+	defaultVolumeSizeLimit = resource.MustParse("10Gi")
+
+	defaultProgressDeadlineSeconds int32 = 600
+	defaultReplicas                int32 = 1
+	defaultBootstrapAttempts       int32 = 3
+	defaultBootstrapRetryDelay     int32 = 10
+	defaultSidecarsShutdownDelay   int32 = 5
+	defaultWordpressShutdownDelay  int32 = 10
+	defaultMediaPreSyncTimeout     int32 = 300
 )
 
-var varLogSizeLimit = resource.MustParse("1Gi")
+// defaultMediaReadinessCheckCmd lists the root of the media bucket.
+func defaultMediaReadinessCheckCmd() []string {
+	return []string{"wp", "eval", "exit(false === @opendir(getenv('STACK_MEDIA_BUCKET')) ? 1 : 0);"}
+}
 
 // SetDefaults sets Wordpress field defaults.
 func (wp *Wordpress) SetDefaults() {
@@ -56,10 +131,28 @@ func (wp *Wordpress) SetDefaults() {
 		wp.Spec.ImagePullPolicy = corev1.PullAlways
 	}
 
+	if wp.Spec.ContainerName == "" {
+		wp.Spec.ContainerName = "wordpress"
+	}
+
+	if wp.Spec.CLIContainerName == "" {
+		wp.Spec.CLIContainerName = "wp-cli"
+	}
+
+	if wp.Spec.TerminationMessagePolicy == "" {
+		wp.Spec.TerminationMessagePolicy = defaultTerminationMessagePolicy
+	}
+
 	if wp.Spec.CodeVolumeSpec != nil && wp.Spec.CodeVolumeSpec.MountPath == "" {
 		wp.Spec.CodeVolumeSpec.MountPath = defaultCodeMountPath
 	}
 
+	if wp.Spec.CodeVolumeSpec != nil && wp.Spec.CodeVolumeSpec.GitDir != nil &&
+		wp.Spec.CodeVolumeSpec.GitDir.Mirror && wp.Spec.CodeVolumeSpec.GitDir.WorktreeSubdir == "" {
+		// keep the mirror out of the served code
+		wp.Spec.CodeVolumeSpec.GitDir.WorktreeSubdir = defaultMirrorWorktreeSubdir
+	}
+
 	if wp.Spec.CodeVolumeSpec != nil && wp.Spec.CodeVolumeSpec.ContentSubPath == "" {
 		wp.Spec.CodeVolumeSpec.ContentSubPath = defaultRepoCodeSubPath
 	}
@@ -76,7 +169,154 @@ func (wp *Wordpress) SetDefaults() {
 		}
 	}
 
+	if wp.Spec.MediaVolumeSpec != nil && wp.Spec.MediaVolumeSpec.PreSync != nil {
+		setMediaPreSyncDefaults(wp.Spec.MediaVolumeSpec.PreSync)
+	}
+
+	if wp.Spec.CodeVolumeSpec != nil && !wp.Spec.CodeVolumeSpec.ReadOnly {
+		setHostPathTypeDefault(wp.Spec.CodeVolumeSpec.HostPath)
+	}
+
+	if wp.Spec.MediaVolumeSpec != nil && !wp.Spec.MediaVolumeSpec.ReadOnly {
+		setHostPathTypeDefault(wp.Spec.MediaVolumeSpec.HostPath)
+	}
+
 	if wp.Spec.WordpressPathPrefix == "" {
 		wp.Spec.WordpressPathPrefix = "/wp"
 	}
+
+	if wp.Spec.WordpressBootstrapSpec != nil && wp.Spec.WordpressBootstrapSpec.Attempts == 0 {
+		wp.Spec.WordpressBootstrapSpec.Attempts = defaultBootstrapAttempts
+	}
+
+	if wp.Spec.WordpressBootstrapSpec != nil && wp.Spec.WordpressBootstrapSpec.RetryDelaySeconds == nil {
+		retryDelay := defaultBootstrapRetryDelay
+		wp.Spec.WordpressBootstrapSpec.RetryDelaySeconds = &retryDelay
+	}
+
+	if wp.Spec.DefaultVolumeSizeLimit == nil {
+		sizeLimit := defaultVolumeSizeLimit.DeepCopy()
+		wp.Spec.DefaultVolumeSizeLimit = &sizeLimit
+	}
+
+	if wp.Spec.Debug != nil && len(wp.Spec.Debug.LogPath) == 0 {
+		wp.Spec.Debug.LogPath = defaultDebugLogPath
+	}
+
+	if wp.Spec.MediaReadinessCheck != nil && len(wp.Spec.MediaReadinessCheck.Command) == 0 {
+		wp.Spec.MediaReadinessCheck.Command = defaultMediaReadinessCheckCmd()
+	}
+
+	if wp.Spec.ShutdownOrder != nil {
+		if wp.Spec.ShutdownOrder.SidecarsDelaySeconds == nil {
+			delay := defaultSidecarsShutdownDelay
+			wp.Spec.ShutdownOrder.SidecarsDelaySeconds = &delay
+		}
+
+		if wp.Spec.ShutdownOrder.WordpressDelaySeconds == nil {
+			delay := defaultWordpressShutdownDelay
+			wp.Spec.ShutdownOrder.WordpressDelaySeconds = &delay
+		}
+	}
+
+	if wp.Spec.FPMExporter != nil {
+		if len(wp.Spec.FPMExporter.Image) == 0 {
+			wp.Spec.FPMExporter.Image = defaultFPMExporterImage
+		}
+
+		if len(wp.Spec.FPMExporter.Socket) == 0 {
+			wp.Spec.FPMExporter.Socket = defaultFPMExporterSocket
+		}
+
+		if len(wp.Spec.FPMExporter.StatusPath) == 0 {
+			wp.Spec.FPMExporter.StatusPath = defaultFPMExporterStatusPath
+		}
+
+		// the exporter reaches php-fpm over the shared socket volume
+		if wp.Spec.SharedSocketVolume == nil {
+			wp.Spec.SharedSocketVolume = &wordpressv1alpha1.SharedSocketVolumeSpec{}
+		}
+	}
+
+	if wp.Spec.SharedSocketVolume != nil && len(wp.Spec.SharedSocketVolume.MountPath) == 0 {
+		wp.Spec.SharedSocketVolume.MountPath = defaultSharedSocketMountPath
+	}
+
+	if wp.Spec.SharedSessionVolume != nil && len(wp.Spec.SharedSessionVolume.MountPath) == 0 {
+		wp.Spec.SharedSessionVolume.MountPath = defaultSharedSessionMountPath
+	}
+
+	if len(wp.Spec.VolumePermissionsMode) == 0 {
+		// the restricted profile doesn't allow running the chown container as root
+		if wp.Spec.SecurityProfile == wordpressv1alpha1.SecurityProfileRestricted {
+			wp.Spec.VolumePermissionsMode = wordpressv1alpha1.VolumePermissionsFSGroup
+		} else {
+			wp.Spec.VolumePermissionsMode = wordpressv1alpha1.VolumePermissionsChown
+		}
+	}
+
+	if wp.Spec.VaultAgent != nil {
+		if len(wp.Spec.VaultAgent.Image) == 0 {
+			wp.Spec.VaultAgent.Image = defaultVaultAgentImage
+		}
+
+		if len(wp.Spec.VaultAgent.MountPath) == 0 {
+			wp.Spec.VaultAgent.MountPath = defaultVaultSecretsMountPath
+		}
+
+		if len(wp.Spec.VaultAgent.SecretsFile) == 0 {
+			wp.Spec.VaultAgent.SecretsFile = defaultVaultSecretsFile
+		}
+	}
+
+	if wp.Spec.VPA != nil && wp.Spec.VPA.UpdateMode == "" {
+		wp.Spec.VPA.UpdateMode = "Off"
+	}
+
+	if replicas, err := wp.annotationReplicas(); err == nil && replicas != nil {
+		wp.Spec.Replicas = replicas
+	}
+
+	if wp.Spec.Replicas == nil {
+		replicas := defaultReplicas
+		wp.Spec.Replicas = &replicas
+	}
+
+	if *wp.Spec.Replicas < options.MinReplicas && !wp.Spec.Paused {
+		replicas := options.MinReplicas
+		wp.Spec.Replicas = &replicas
+	}
+
+	if wp.Spec.ProgressDeadlineSeconds == nil {
+		progressDeadlineSeconds := defaultProgressDeadlineSeconds
+		wp.Spec.ProgressDeadlineSeconds = &progressDeadlineSeconds
+	}
+}
+
+// setHostPathTypeDefault makes writable host path volumes create the directory
+// on first boot, if no explicit type is set.
+func setHostPathTypeDefault(hostPath *corev1.HostPathVolumeSource) {
+	if hostPath == nil || hostPath.Type != nil {
+		return
+	}
+
+	hostPathType := corev1.HostPathDirectoryOrCreate
+	hostPath.Type = &hostPathType
+}
+
+// setMediaPreSyncDefaults sets the image, cache mount path and timeout of the
+// media pre-sync init container, if not set.
+func setMediaPreSyncDefaults(preSync *wordpressv1alpha1.MediaPreSyncSpec) {
+	if len(preSync.Image) == 0 {
+		preSync.Image = defaultMediaPreSyncImage
+	}
+
+	if len(preSync.MountPath) == 0 {
+		preSync.MountPath = defaultMediaPreSyncMountPath
+	}
+
+	if preSync.TimeoutSeconds == nil {
+		timeout := defaultMediaPreSyncTimeout
+		preSync.TimeoutSeconds = &timeout
+	}
 }
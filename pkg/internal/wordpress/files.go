@@ -0,0 +1,162 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wordpress
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const fileVolumeNameFmt = "file-%d"
+
+var (
+	errInvalidFileSource = errors.New("exactly one of configMapKeyRef or secretKeyRef must be set")
+	errInvalidFilePath   = errors.New("invalid file path")
+)
+
+// ValidateFiles checks that .spec.files entries have a single source and that
+// their paths don't collide with each other or with the operator managed mounts.
+func (wp *Wordpress) ValidateFiles() error {
+	reserved := wp.reservedMountPaths()
+	seen := map[string]bool{}
+
+	for i, f := range wp.Spec.Files {
+		if (f.ConfigMapKeyRef == nil) == (f.SecretKeyRef == nil) {
+			return fmt.Errorf(".spec.files[%d]: %w", i, errInvalidFileSource)
+		}
+
+		p := path.Clean(f.Path)
+		if !path.IsAbs(p) {
+			return fmt.Errorf("%w: .spec.files[%d]: %s is not absolute", errInvalidFilePath, i, f.Path)
+		}
+
+		if seen[p] {
+			return fmt.Errorf("%w: .spec.files[%d]: %s is already mounted", errInvalidFilePath, i, f.Path)
+		}
+
+		seen[p] = true
+
+		for _, r := range reserved {
+			if p == r || strings.HasPrefix(p, r+"/") {
+				return fmt.Errorf("%w: .spec.files[%d]: %s collides with reserved mount %s", errInvalidFilePath, i, f.Path, r)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (wp *Wordpress) reservedMountPaths() []string {
+	paths := []string{
+		knativeVarLogMountPath,
+		knativeInternalMountPath,
+		podInfoMountPath,
+		gitSSHKeyMountPath,
+		gitCABundleMountPath,
+		gitVerifyKeyMountPath,
+		jobOutputMountPath,
+	}
+
+	if wp.Spec.VaultAgent != nil {
+		paths = append(paths, wp.Spec.VaultAgent.MountPath)
+	}
+
+	if wp.hasMediaToken() {
+		paths = append(paths, mediaTokenMountPath)
+	}
+
+	if wp.hasMediaPreSync() {
+		paths = append(paths, wp.Spec.MediaVolumeSpec.PreSync.MountPath)
+	}
+
+	if wp.hasCodeMounts() {
+		paths = append(paths, codeSrcMountPath, configMountPath, wp.Spec.CodeVolumeSpec.MountPath)
+	} else if wp.hasConfigGitDir() {
+		paths = append(paths, configMountPath)
+	}
+
+	if wp.hasMediaMounts() {
+		paths = append(paths, wp.Spec.MediaVolumeSpec.MountPath)
+	}
+
+	return paths
+}
+
+func (wp *Wordpress) fileVolumes() []corev1.Volume {
+	volumes := []corev1.Volume{}
+
+	for i, f := range wp.Spec.Files {
+		v := corev1.Volume{
+			Name: fmt.Sprintf(fileVolumeNameFmt, i),
+		}
+
+		switch {
+		case f.ConfigMapKeyRef != nil:
+			v.VolumeSource.ConfigMap = &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: f.ConfigMapKeyRef.LocalObjectReference,
+				Items: []corev1.KeyToPath{
+					{Key: f.ConfigMapKeyRef.Key, Path: f.ConfigMapKeyRef.Key},
+				},
+				Optional: f.ConfigMapKeyRef.Optional,
+			}
+		case f.SecretKeyRef != nil:
+			v.VolumeSource.Secret = &corev1.SecretVolumeSource{
+				SecretName: f.SecretKeyRef.Name,
+				Items: []corev1.KeyToPath{
+					{Key: f.SecretKeyRef.Key, Path: f.SecretKeyRef.Key},
+				},
+				Optional: f.SecretKeyRef.Optional,
+			}
+		default:
+			continue
+		}
+
+		volumes = append(volumes, v)
+	}
+
+	return volumes
+}
+
+func (wp *Wordpress) fileVolumeMounts() []corev1.VolumeMount {
+	mounts := []corev1.VolumeMount{}
+
+	for i, f := range wp.Spec.Files {
+		var key string
+
+		switch {
+		case f.ConfigMapKeyRef != nil:
+			key = f.ConfigMapKeyRef.Key
+		case f.SecretKeyRef != nil:
+			key = f.SecretKeyRef.Key
+		default:
+			continue
+		}
+
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      fmt.Sprintf(fileVolumeNameFmt, i),
+			MountPath: f.Path,
+			SubPath:   key,
+			ReadOnly:  true,
+		})
+	}
+
+	return mounts
+}
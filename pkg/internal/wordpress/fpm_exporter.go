@@ -0,0 +1,84 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wordpress
+
+import (
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// fpmExporterProbe checks that the exporter serves on the prometheus port
+func fpmExporterProbe() *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			TCPSocket: &corev1.TCPSocketAction{
+				Port: intstr.FromString("prometheus"),
+			},
+		},
+		FailureThreshold: 3,
+		PeriodSeconds:    10,
+		SuccessThreshold: 1,
+		TimeoutSeconds:   5,
+	}
+}
+
+func (wp *Wordpress) fpmExporterContainer() corev1.Container {
+	socket := path.Join(wp.Spec.SharedSocketVolume.MountPath, wp.Spec.FPMExporter.Socket)
+
+	c := corev1.Container{
+		Name:  "fpm-exporter",
+		Image: wp.Spec.FPMExporter.Image,
+		Env: []corev1.EnvVar{
+			{
+				Name:  "PHP_FPM_SCRAPE_URI",
+				Value: fmt.Sprintf("unix://%s;%s", socket, wp.Spec.FPMExporter.StatusPath),
+			},
+			{
+				Name:  "PHP_FPM_WEB_LISTEN_ADDRESS",
+				Value: fmt.Sprintf(":%d", MetricsExporterPort),
+			},
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "prometheus",
+				ContainerPort: MetricsExporterPort,
+			},
+		},
+		Resources:                wp.Spec.FPMExporter.Resources,
+		TerminationMessagePolicy: wp.Spec.TerminationMessagePolicy,
+		VolumeMounts:             []corev1.VolumeMount{wp.sharedSocketVolumeMount()},
+		LivenessProbe:            fpmExporterProbe(),
+		ReadinessProbe:           fpmExporterProbe(),
+	}
+
+	if wp.Spec.FPMExporter.LivenessProbe != nil {
+		c.LivenessProbe = wp.Spec.FPMExporter.LivenessProbe
+	}
+
+	if wp.Spec.FPMExporter.ReadinessProbe != nil {
+		c.ReadinessProbe = wp.Spec.FPMExporter.ReadinessProbe
+	}
+
+	if wp.Spec.HardenSidecars {
+		c.SecurityContext = wp.sidecarSecurityContext()
+	}
+
+	return c
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wordpress
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bitpoke/wordpress-operator/pkg/cmd/options"
+)
+
+// ImageTagAnnotation records the image tag on pods, when the image is pinned by digest.
+const ImageTagAnnotation = "wordpress.bitpoke.io/image-tag"
+
+var (
+	errInvalidImage = errors.New("invalid image reference")
+
+	imageNameRegexp   = regexp.MustCompile(`^[a-z0-9]+([._/:-]+[a-z0-9]+)*$`)
+	imageTagRegexp    = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	imageDigestRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*([-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}$`)
+)
+
+// parseImage splits an image reference in the form of name[:tag][@digest].
+func parseImage(image string) (name, tag, digest string, err error) {
+	name = image
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, digest = name[:i], name[i+1:]
+
+		if !imageDigestRegexp.MatchString(digest) {
+			return "", "", "", fmt.Errorf("%w: %q has a malformed digest", errInvalidImage, image)
+		}
+	}
+
+	// a colon after the last slash separates the tag, otherwise it's a registry port
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+
+		if !imageTagRegexp.MatchString(tag) {
+			return "", "", "", fmt.Errorf("%w: %q has a malformed tag", errInvalidImage, image)
+		}
+	}
+
+	if !imageNameRegexp.MatchString(name) {
+		return "", "", "", fmt.Errorf("%w: %q has a malformed name", errInvalidImage, image)
+	}
+
+	return name, tag, digest, nil
+}
+
+// ValidateImage checks that .spec.image and .spec.canary.image are valid image references.
+func (wp *Wordpress) ValidateImage() error {
+	if _, _, _, err := parseImage(wp.Spec.Image); err != nil {
+		return err
+	}
+
+	if wp.Spec.Canary != nil {
+		if _, _, _, err := parseImage(wp.Spec.Canary.Image); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// image returns the image used for wordpress containers. Images pinned by
+// both tag and digest are pulled by digest only.
+func (wp *Wordpress) image() string {
+	name, tag, digest, err := parseImage(wp.Spec.Image)
+	if err != nil || tag == "" || digest == "" {
+		return wp.Spec.Image
+	}
+
+	return fmt.Sprintf("%s@%s", name, digest)
+}
+
+// ImageTag returns the image tag, when the image is pinned by both tag and digest.
+func (wp *Wordpress) ImageTag() string {
+	_, tag, digest, err := parseImage(wp.Spec.Image)
+	if err != nil || digest == "" {
+		return ""
+	}
+
+	return tag
+}
+
+func (wp *Wordpress) setImageTagAnnotation(out *corev1.PodTemplateSpec) {
+	tag := wp.ImageTag()
+	if tag == "" {
+		return
+	}
+
+	if out.Annotations == nil {
+		out.Annotations = make(map[string]string)
+	}
+
+	out.Annotations[ImageTagAnnotation] = tag
+}
+
+// mirrorImage rewrites the registry prefix of an image to its mirror, as
+// configured by the operator. The longest matching prefix wins.
+func mirrorImage(image string) string {
+	if len(options.RegistryMirrors) == 0 {
+		return image
+	}
+
+	name := normalizeImage(image)
+	prefix, mirror := "", ""
+
+	for p, m := range options.RegistryMirrors {
+		p = strings.TrimSuffix(p, "/")
+		if strings.HasPrefix(name, p+"/") && len(p) > len(prefix) {
+			prefix, mirror = p, strings.TrimSuffix(m, "/")
+		}
+	}
+
+	if prefix == "" {
+		return image
+	}
+
+	return mirror + strings.TrimPrefix(name, prefix)
+}
+
+// normalizeImage returns the image with an explicit registry, using the
+// docker.io defaults (eg. busybox is docker.io/library/busybox).
+func normalizeImage(image string) string {
+	i := strings.Index(image, "/")
+	if i < 0 {
+		return "docker.io/library/" + image
+	}
+
+	if registry := image[:i]; !strings.ContainsAny(registry, ".:") && registry != "localhost" {
+		return "docker.io/" + image
+	}
+
+	return image
+}
+
+// mirrorImages rewrites the images of the pod containers to their mirrors.
+func mirrorImages(spec *corev1.PodSpec) {
+	for i := range spec.InitContainers {
+		spec.InitContainers[i].Image = mirrorImage(spec.InitContainers[i].Image)
+	}
+
+	for i := range spec.Containers {
+		spec.Containers[i].Image = mirrorImage(spec.Containers[i].Image)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wordpress
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	wordpressv1alpha1 "github.com/bitpoke/wordpress-operator/pkg/apis/wordpress/v1alpha1"
+)
+
+type initResourcePreset struct {
+	cpuRequest, cpuLimit       string
+	memoryRequest, memoryLimit string
+	// cloning big repositories takes more memory than the other init containers
+	cloneMemoryRequest, cloneMemoryLimit string
+}
+
+var initResourcePresets = map[wordpressv1alpha1.InitResourceProfile]initResourcePreset{
+	wordpressv1alpha1.InitResourceProfileSmall:  {"50m", "500m", "64Mi", "256Mi", "64Mi", "256Mi"},
+	wordpressv1alpha1.InitResourceProfileMedium: {"100m", "1", "128Mi", "512Mi", "256Mi", "1Gi"},
+	wordpressv1alpha1.InitResourceProfileLarge:  {"250m", "2", "256Mi", "1Gi", "512Mi", "2Gi"},
+}
+
+func (p initResourcePreset) resources(clone bool) corev1.ResourceRequirements {
+	memoryRequest, memoryLimit := p.memoryRequest, p.memoryLimit
+	if clone {
+		memoryRequest, memoryLimit = p.cloneMemoryRequest, p.cloneMemoryLimit
+	}
+
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(p.cpuRequest),
+			corev1.ResourceMemory: resource.MustParse(memoryRequest),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(p.cpuLimit),
+			corev1.ResourceMemory: resource.MustParse(memoryLimit),
+		},
+	}
+}
+
+// setInitResources sets the .spec.initResourceProfile resources on the init
+// containers which don't set any.
+func (wp *Wordpress) setInitResources(spec *corev1.PodSpec) {
+	preset, ok := initResourcePresets[wp.Spec.InitResourceProfile]
+	if !ok {
+		return
+	}
+
+	for i := range spec.InitContainers {
+		c := &spec.InitContainers[i]
+		if len(c.Resources.Requests) > 0 || len(c.Resources.Limits) > 0 {
+			continue
+		}
+
+		c.Resources = preset.resources(c.Name == "git" || c.Name == "git-config")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wordpress
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// mediaPreSyncScript copies STACK_MEDIA_BUCKET into the cache volume, using
+// the credentials set by mediaEnv. Failures and timeouts only leave the cache
+// incomplete, since the media files are still served from the bucket.
+const mediaPreSyncScript = `set -e
+
+export HOME="$(mktemp -d)"
+bucket="${STACK_MEDIA_BUCKET#*://}"
+
+case "$STACK_MEDIA_BUCKET" in
+s3://*)
+    export RCLONE_S3_PROVIDER="${RCLONE_S3_PROVIDER:-AWS}"
+    export RCLONE_S3_ENV_AUTH=true
+    export RCLONE_S3_FORCE_PATH_STYLE="${S3_FORCE_PATH_STYLE:-false}"
+    test -z "$AWS_REGION" || export RCLONE_S3_REGION="$AWS_REGION"
+    test -z "$S3_ENDPOINT" || export RCLONE_S3_ENDPOINT="$S3_ENDPOINT"
+    remote=":s3:$bucket"
+    ;;
+gs://*)
+    export RCLONE_GCS_ENV_AUTH=true
+    test -z "$GOOGLE_CREDENTIALS" || export RCLONE_GCS_SERVICE_ACCOUNT_CREDENTIALS="$GOOGLE_CREDENTIALS"
+    remote=":gcs:$bucket"
+    ;;
+*)
+    echo "unsupported media bucket: $STACK_MEDIA_BUCKET" >&2
+    exit 1
+    ;;
+esac
+
+if [ -n "$MEDIA_PRESYNC_PREFIX" ] ; then
+    remote="$remote/$MEDIA_PRESYNC_PREFIX"
+fi
+
+rc=0
+timeout "$MEDIA_PRESYNC_TIMEOUT" rclone copy --config /dev/null "$remote" "$MEDIA_PRESYNC_DIR/$MEDIA_PRESYNC_PREFIX" || rc=$?
+
+if [ "$rc" -ne 0 ] ; then
+    echo "media pre-sync stopped with exit code $rc, starting with a partial cache" >&2
+fi
+`
+
+func (wp *Wordpress) hasMediaPreSync() bool {
+	return wp.Spec.MediaVolumeSpec != nil && wp.Spec.MediaVolumeSpec.PreSync != nil
+}
+
+func (wp *Wordpress) mediaPreSyncContainer() corev1.Container {
+	preSync := wp.Spec.MediaVolumeSpec.PreSync
+
+	// the site env is needed for expanding the media path prefix, and ends
+	// with the media credentials from mediaEnv
+	env := append(wp.env(), []corev1.EnvVar{
+		{
+			Name:  "MEDIA_PRESYNC_DIR",
+			Value: preSync.MountPath,
+		},
+		{
+			Name:  "MEDIA_PRESYNC_PREFIX",
+			Value: preSync.Prefix,
+		},
+		{
+			Name:  "MEDIA_PRESYNC_TIMEOUT",
+			Value: fmt.Sprintf("%d", *preSync.TimeoutSeconds),
+		},
+	}...)
+
+	// file credentials (eg. GOOGLE_APPLICATION_CREDENTIALS or AWS_CONFIG_FILE)
+	// are found at the same paths as in the wordpress container
+	mounts := []corev1.VolumeMount{wp.mediaCacheVolumeMount()}
+	mounts = append(mounts, wp.Spec.VolumeMounts...)
+	mounts = append(mounts, wp.fileVolumeMounts()...)
+
+	if wp.Spec.VaultAgent != nil {
+		mounts = append(mounts, wp.vaultSecretsVolumeMount())
+	}
+
+	if wp.hasMediaToken() {
+		mounts = append(mounts, corev1.VolumeMount{
+			MountPath: mediaTokenMountPath,
+			Name:      mediaTokenVolume,
+			ReadOnly:  true,
+		})
+	}
+
+	return corev1.Container{
+		Name:                     "media-presync",
+		Image:                    preSync.Image,
+		Command:                  []string{"/bin/sh", "-c", mediaPreSyncScript},
+		Env:                      env,
+		EnvFrom:                  wp.envFrom(),
+		Resources:                preSync.Resources,
+		SecurityContext:          wp.securityContext(),
+		TerminationMessagePolicy: wp.Spec.TerminationMessagePolicy,
+		VolumeMounts:             mounts,
+	}
+}
+
+func (wp *Wordpress) mediaCacheVolumeMount() corev1.VolumeMount {
+	return corev1.VolumeMount{
+		MountPath: wp.Spec.MediaVolumeSpec.PreSync.MountPath,
+		Name:      mediaCacheVolume,
+	}
+}
+
+func (wp *Wordpress) mediaCacheVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: mediaCacheVolume,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{
+				SizeLimit: wp.Spec.MediaVolumeSpec.PreSync.SizeLimit,
+			},
+		},
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wordpress
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodPlan summarizes the optional features the web pod template contains.
+type PodPlan struct {
+	// InitContainers are the init container names, in order.
+	InitContainers []string `json:"initContainers"`
+	PrepareVolumes bool     `json:"prepareVolumes"`
+	GitClone       bool     `json:"gitClone"`
+	InstallWP      bool     `json:"installWP"`
+	Migrations     bool     `json:"migrations"`
+	// Volumes maps the pod volume names to their source (eg. emptyDir).
+	Volumes map[string]string `json:"volumes"`
+	// VolumeMounts maps the wordpress container mount paths to volume names.
+	VolumeMounts map[string]string `json:"volumeMounts"`
+	// MediaProvider is s3, gcs or the media volume source, if media is set.
+	MediaProvider  string    `json:"mediaProvider,omitempty"`
+	ReadinessProbe ProbePlan `json:"readinessProbe"`
+	LivenessProbe  ProbePlan `json:"livenessProbe"`
+	StartupProbe   ProbePlan `json:"startupProbe"`
+}
+
+// ProbePlan summarizes a probe of the wordpress container.
+type ProbePlan struct {
+	// Custom is true if the probe is set in the Wordpress spec.
+	Custom bool `json:"custom"`
+	// Action describes the probe handler (eg. HTTPS GET :8080/).
+	Action              string `json:"action"`
+	InitialDelaySeconds int32  `json:"initialDelaySeconds"`
+	PeriodSeconds       int32  `json:"periodSeconds"`
+	TimeoutSeconds      int32  `json:"timeoutSeconds"`
+	FailureThreshold    int32  `json:"failureThreshold"`
+}
+
+// PodPlan computes the summary of the web pod template, from the generated
+// template itself.
+func (wp *Wordpress) PodPlan() PodPlan {
+	spec := wp.WebPodTemplateSpec().Spec
+	c := spec.Containers[0]
+
+	plan := PodPlan{
+		InitContainers: []string{},
+		Volumes:        map[string]string{},
+		VolumeMounts:   map[string]string{},
+		ReadinessProbe: probePlan(c.ReadinessProbe, wp.Spec.ReadinessProbe != nil),
+		LivenessProbe:  probePlan(c.LivenessProbe, wp.Spec.LivenessProbe != nil),
+		StartupProbe:   probePlan(c.StartupProbe, wp.Spec.StartupProbe != nil),
+	}
+
+	for _, ic := range spec.InitContainers {
+		plan.InitContainers = append(plan.InitContainers, ic.Name)
+
+		switch ic.Name {
+		case "prepare-volumes":
+			plan.PrepareVolumes = true
+		case "git":
+			plan.GitClone = true
+		case "install-wp":
+			plan.InstallWP = true
+		case "migrations":
+			plan.Migrations = true
+		}
+	}
+
+	for _, v := range spec.Volumes {
+		plan.Volumes[v.Name] = volumeSourceName(v.VolumeSource)
+	}
+
+	for _, m := range c.VolumeMounts {
+		plan.VolumeMounts[m.MountPath] = m.Name
+	}
+
+	plan.MediaProvider = plan.Volumes[mediaVolumeName]
+
+	for _, e := range c.Env {
+		if e.Name == "STACK_MEDIA_BUCKET" {
+			plan.MediaProvider = strings.SplitN(e.Value, "://", 2)[0]
+		}
+	}
+
+	if plan.MediaProvider == gcsPrefix {
+		plan.MediaProvider = "gcs"
+	}
+
+	return plan
+}
+
+// volumeSourceName returns the name of the volume source field which is set (eg. emptyDir).
+func volumeSourceName(src corev1.VolumeSource) string {
+	var fields map[string]json.RawMessage
+
+	b, err := json.Marshal(src)
+	if err != nil || json.Unmarshal(b, &fields) != nil {
+		return ""
+	}
+
+	for name := range fields {
+		return name
+	}
+
+	return ""
+}
+
+func probePlan(probe *corev1.Probe, custom bool) ProbePlan {
+	if probe == nil {
+		return ProbePlan{}
+	}
+
+	plan := ProbePlan{
+		Custom:              custom,
+		InitialDelaySeconds: probe.InitialDelaySeconds,
+		PeriodSeconds:       probe.PeriodSeconds,
+		TimeoutSeconds:      probe.TimeoutSeconds,
+		FailureThreshold:    probe.FailureThreshold,
+	}
+
+	switch {
+	case probe.HTTPGet != nil:
+		scheme := probe.HTTPGet.Scheme
+		if scheme == "" {
+			scheme = corev1.URISchemeHTTP
+		}
+
+		plan.Action = fmt.Sprintf("%s GET :%s%s", scheme, probe.HTTPGet.Port.String(), probe.HTTPGet.Path)
+	case probe.TCPSocket != nil:
+		plan.Action = fmt.Sprintf("TCP :%s", probe.TCPSocket.Port.String())
+	case probe.Exec != nil:
+		plan.Action = fmt.Sprintf("exec %s", strings.Join(probe.Exec.Command, " "))
+	}
+
+	return plan
+}
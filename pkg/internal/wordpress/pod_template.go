@@ -18,27 +18,54 @@ package wordpress
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"path"
 	"strings"
 	"text/template"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
+	"github.com/appscode/mergo"
+
+	wordpressv1alpha1 "github.com/bitpoke/wordpress-operator/pkg/apis/wordpress/v1alpha1"
 	"github.com/bitpoke/wordpress-operator/pkg/cmd/options"
 )
 
+var (
+	errGitMediaMount       = errors.New("can't mount media in the git clone container")
+	errGitSchemeNotAllowed = errors.New("git repository scheme is not allowed")
+	errSharedSocketPath    = errors.New("invalid shared socket volume mount path")
+	errConflictingMedia    = errors.New("conflicting media sources")
+	errInvalidMediaPrefix  = errors.New("invalid media path prefix")
+	errInvalidMediaToken   = errors.New("invalid media token")
+	errConfigMountPath     = errors.New("invalid code volume mount path")
+	errSharedSessionVolume = errors.New("invalid shared session volume")
+	errProbeDefaults       = errors.New("invalid probe defaults")
+	errMediaPreSync        = errors.New("invalid media pre-sync")
+	errSecurityProfile     = errors.New("invalid security profile")
+	errWarmup              = errors.New("invalid warmup")
+)
+
 const (
 	// InternalHTTPPort represents the internal port used by the runtime container.
 	InternalHTTPPort = 8080
 	// MetricsExporterPort represents the exposed port where metrics can be found.
 	MetricsExporterPort = 9145
-	codeVolumeName      = "code"
-	mediaVolumeName     = "media"
-	s3Prefix            = "s3"
-	gcsPrefix           = "gs"
+	// GitCommitAnnotation records the git reference of the code on pods,
+	// which is the commit hash when GitRef pins one, and HEAD for the
+	// repository default branch.
+	GitCommitAnnotation = "wordpress.bitpoke.io/git-commit"
+	// ForceRecloneAnnotation forces a new git clone, when set to a new value,
+	// for code volumes with SkipIfPopulated.
+	ForceRecloneAnnotation = "wordpress.presslabs.org/force-reclone"
+	codeVolumeName         = "code"
+	mediaVolumeName        = "media"
+	s3Prefix               = "s3"
+	gcsPrefix              = "gs"
 
 	prepareVolumesImage = "gcr.io/google-containers/busybox@sha256:545e6a6310a27636260920bc07b994a299b6708a1b26910cfefd335fdfb60d2b"
 )
@@ -52,7 +79,9 @@ export GIT_SSH_COMMAND="ssh -o UserKnownHostsFile=$HOME/.ssh/knonw_hosts -o Stri
 
 test -d "$HOME/.ssh" || mkdir "$HOME/.ssh"
 
-if [ ! -z "$SSH_RSA_PRIVATE_KEY" ] ; then
+if [ -f "$SSH_KEY_FILE" ] ; then
+    export GIT_SSH_COMMAND="$GIT_SSH_COMMAND -o IdentityFile=$SSH_KEY_FILE"
+elif [ ! -z "$SSH_RSA_PRIVATE_KEY" ] ; then
     echo "$SSH_RSA_PRIVATE_KEY" > "$HOME/.ssh/id_rsa"
     chmod 0400 "$HOME/.ssh/id_rsa"
     export GIT_SSH_COMMAND="$GIT_SSH_COMMAND -o IdentityFile=$HOME/.ssh/id_rsa"
@@ -63,23 +92,156 @@ if [ -z "$GIT_CLONE_URL" ] ; then
     exit 1
 fi
 
-find "$SRC_DIR" -maxdepth 1 -mindepth 1 -print0 | xargs -0 /bin/rm -rf
+# verify_signature checks that the code checked out in $1 is signed by one of
+# the keys in $GIT_VERIFY_KEY_FILE, if set
+verify_signature() {
+    if [ -z "$GIT_VERIFY_KEY_FILE" ] ; then
+        return 0
+    fi
+    export GNUPGHOME="$(mktemp -d)"
+    gpg --batch --quiet --import "$GIT_VERIFY_KEY_FILE"
+    if [ ! -z "$GIT_CLONE_REF" ] && git -C "$1" rev-parse -q --verify "refs/tags/$GIT_CLONE_REF" >/dev/null ; then
+        git -C "$1" verify-tag "$GIT_CLONE_REF"
+    else
+        git -C "$1" verify-commit HEAD
+    fi
+}
+
+if [ "$GIT_CLONE_SKIP_IF_POPULATED" = "true" ] && git -C "$SRC_DIR" rev-parse --git-dir >/dev/null 2>&1 ; then
+    # keep the existing clone if it is of the same repository, at the same
+    # reference, was completed and no reclone was requested since
+    if [ "$(git -C "$SRC_DIR" remote get-url origin)" = "$GIT_CLONE_URL" ] && \
+        [ "$(git -C "$SRC_DIR" config --get wordpress-operator.reclone)" = "$GIT_CLONE_RECLONE" ] && \
+        WANTED="$(git -C "$SRC_DIR" rev-parse --verify -q "${GIT_CLONE_REF:-HEAD}^{commit}")" && \
+        [ "$(git -C "$SRC_DIR" rev-parse HEAD)" = "$WANTED" ] && \
+        [ "$(git -C "$SRC_DIR" config --get wordpress-operator.populated)" = "$WANTED" ] ; then
+        verify_signature "$SRC_DIR"
+        echo "$SRC_DIR is already populated at ${GIT_CLONE_REF:-HEAD}, skipping the clone"
+        exit 0
+    fi
+    # the clone is incomplete until marked populated again
+    git -C "$SRC_DIR" config --unset wordpress-operator.populated || true
+fi
+
+mkdir -p "$SRC_DIR"
+CLONE_DIR="$SRC_DIR"
+if [ "$GIT_CLONE_ATOMIC" = "true" ] ; then
+    # clone next to the existing code, which gets replaced only on success
+    CLONE_DIR="$SRC_DIR/.git-clone"
+    rm -rf "$CLONE_DIR"
+else
+    find "$SRC_DIR" -maxdepth 1 -mindepth 1 -print0 | xargs -0 /bin/rm -rf
+fi
+
+GIT_CLONE_ARGS=()
+if [ ! -z "$GIT_CLONE_REFERENCE" ] ; then
+    if git -C "$GIT_CLONE_REFERENCE" rev-parse --git-dir >/dev/null 2>&1 ; then
+        GIT_CLONE_ARGS=(--reference "$GIT_CLONE_REFERENCE" --dissociate)
+    else
+        echo "No git repository found at $GIT_CLONE_REFERENCE, cloning without reference" >&2
+    fi
+fi
 
 set -x
-git clone "$GIT_CLONE_URL" "$SRC_DIR"
-cd "$SRC_DIR"
-git checkout -B "$GIT_CLONE_REF" "origin/$GIT_CLONE_REF"
+if [ "$GIT_CLONE_MIRROR" = "true" ] ; then
+    if git -C "$GIT_MIRROR_DIR" rev-parse --git-dir >/dev/null 2>&1 ; then
+        git -C "$GIT_MIRROR_DIR" remote set-url origin "$GIT_CLONE_URL"
+        git -C "$GIT_MIRROR_DIR" fetch --prune origin
+    else
+        git clone --mirror "${GIT_CLONE_ARGS[@]}" "$GIT_CLONE_URL" "$GIT_MIRROR_DIR"
+    fi
+    # mirror worktrees are always detached, since fetching into the mirror
+    # refuses to update branches checked out in a worktree
+    git -C "$GIT_MIRROR_DIR" worktree prune
+    git -C "$GIT_MIRROR_DIR" worktree add --force --detach "$CLONE_DIR" "${GIT_CLONE_REF:-HEAD}"
+else
+    git clone "${GIT_CLONE_ARGS[@]}" "$GIT_CLONE_URL" "$CLONE_DIR"
+    cd "$CLONE_DIR"
+    if [ -z "$GIT_CLONE_REF" ] ; then
+        # no reference set, check out the repository default branch
+        GIT_CLONE_REF="$(git symbolic-ref refs/remotes/origin/HEAD)"
+        GIT_CLONE_REF="${GIT_CLONE_REF#refs/remotes/origin/}"
+    fi
+    if [ "$GIT_CLONE_DETACHED" = "true" ] ; then
+        git checkout --detach "origin/$GIT_CLONE_REF"
+    else
+        git checkout -B "$GIT_CLONE_REF" "origin/$GIT_CLONE_REF"
+    fi
+fi
+
+verify_signature "$CLONE_DIR"
+
+if [ "$#" -gt 0 ] ; then
+    # run the post-clone command, passed as arguments, in the checked out code
+    (cd "$CLONE_DIR" && "$@")
+fi
+
+if [ "$CLONE_DIR" != "$SRC_DIR" ] ; then
+    cd "$SRC_DIR"
+    find "$SRC_DIR" -maxdepth 1 -mindepth 1 ! -name .git-clone -print0 | xargs -0 /bin/rm -rf
+    if [ "$GIT_CLONE_MIRROR" = "true" ] ; then
+        git -C "$GIT_MIRROR_DIR" worktree prune
+    fi
+    find "$CLONE_DIR" -maxdepth 1 -mindepth 1 -exec mv {} "$SRC_DIR" \;
+    rmdir "$CLONE_DIR"
+    if [ "$GIT_CLONE_MIRROR" = "true" ] ; then
+        # point the mirror back to the moved worktree
+        echo "$SRC_DIR/.git" > "$(git rev-parse --git-dir)/gitdir"
+    fi
+fi
+
+# mark the clone populated only once the post-clone command and the move
+# succeeded, along with the reclone request it satisfies
+if [ ! -z "$GIT_CLONE_RECLONE" ] ; then
+    git -C "$SRC_DIR" config wordpress-operator.reclone "$GIT_CLONE_RECLONE"
+else
+    git -C "$SRC_DIR" config --unset wordpress-operator.reclone || true
+fi
+git -C "$SRC_DIR" config wordpress-operator.populated "$(git -C "$SRC_DIR" rev-parse HEAD)"
+`
+
+// installWPScript runs wp-install, passed as $0, with the given arguments,
+// retrying it as configured.
+const installWPScript = `attempt=1
+until "$0" "$@" ; do
+    if [ "$attempt" -ge "$WORDPRESS_BOOTSTRAP_ATTEMPTS" ] ; then
+        echo "$0 failed after $attempt attempts" >&2
+        exit 1
+    fi
+    echo "$0 failed, retrying in $WORDPRESS_BOOTSTRAP_RETRY_DELAY seconds" >&2
+    attempt=$((attempt + 1))
+    sleep "$WORDPRESS_BOOTSTRAP_RETRY_DELAY"
+done
 `
 
+const postStartScriptsCmd = "if test -n \"$POST_START_SCRIPTS\" && command -v run-parts >/dev/null 2>&1 && test -d \"$POST_START_SCRIPTS\"  ; then run-parts --exit-on-error -v \"$POST_START_SCRIPTS\" ; fi" // nolint: lll
+
+const preStopScriptsCmd = "if test -n \"$PRE_STOP_SCRIPTS\" && command -v run-parts >/dev/null 2>&1 && test -d \"$PRE_STOP_SCRIPTS\"  ; then run-parts --exit-on-error -v \"$PRE_STOP_SCRIPTS\" ; fi" // nolint: lll
+
+// preStopScriptsBestEffortCmd runs all the pre-stop scripts, ignoring their
+// failures, for at most 20 seconds, leaving the wordpress container time to
+// stop within the default grace period.
+const preStopScriptsBestEffortCmd = "if test -n \"$PRE_STOP_SCRIPTS\" && command -v run-parts >/dev/null 2>&1 && test -d \"$PRE_STOP_SCRIPTS\"  ; then timeout 20 run-parts -v \"$PRE_STOP_SCRIPTS\" || true ; fi" // nolint: lll
+
+// idleCmd keeps the debug pods running until they are deleted.
+var idleCmd = []string{"sleep", "infinity"}
+
+// jobOutputCaptureCmd runs the wp-cli job command, passed as arguments, teeing
+// its output to the job output volume and keeping its exit code.
+const jobOutputCaptureCmd = `set -o pipefail ; "$@" 2>&1 | tee ` + jobOutputMountPath + `/output.log`
+
 const prepareVolumesScriptTpl = `#!/bin/sh
-test -d /mnt/code && chown {{ .wwwDataUserID }}:{{ .wwwDataUserID }} /mnt/code
-test -d /mnt/media && chown {{ .wwwDataUserID }}:{{ .wwwDataUserID }} /mnt/media
-test -d {{ .knativeVarLogDir }} && chown {{ .wwwDataUserID }}:{{ .wwwDataUserID }} {{ .knativeVarLogDir }}
+{{- if .chown }}
+test -d /mnt/code && chown {{ .wwwDataUserID }}:{{ .runAsGroup }} /mnt/code
+test -d /mnt/media && chown {{ .wwwDataUserID }}:{{ .runAsGroup }} /mnt/media
+test -d {{ .knativeVarLogDir }} && chown {{ .wwwDataUserID }}:{{ .runAsGroup }} {{ .knativeVarLogDir }}
+{{- end }}
 ln -sf ../log {{ .knativeInternalDir }}/${POD_NAMESPACE}_${POD_NAME}_wordpress
 `
 
 var (
 	wwwDataUserID                int64 = 33
+	wwwDataGroupID               int64 = 33
 	prepareVolumesScriptTemplate       = template.Must(template.New("").Parse(prepareVolumesScriptTpl))
 )
 
@@ -89,6 +251,7 @@ var (
 		"AWS_SECRET_ACCESS_KEY": "AWS_SECRET_ACCESS_KEY",
 		"AWS_CONFIG_FILE":       "AWS_CONFIG_FILE",
 		"ENDPOINT":              "S3_ENDPOINT",
+		"REGION":                "AWS_REGION",
 	}
 	gcsEnvVars = map[string]string{
 		"GOOGLE_CREDENTIALS":             "GOOGLE_CREDENTIALS",
@@ -103,14 +266,45 @@ func (wp *Wordpress) mediaEnv() []corev1.EnvVar {
 		return out
 	}
 
+	if wp.hasMediaToken() {
+		out = append(out, corev1.EnvVar{
+			Name:  "AWS_WEB_IDENTITY_TOKEN_FILE",
+			Value: path.Join(mediaTokenMountPath, mediaTokenFileName),
+		}, corev1.EnvVar{
+			Name:  "AWS_ROLE_ARN",
+			Value: wp.Spec.MediaVolumeSpec.RoleARN,
+		})
+	}
+
 	if wp.Spec.MediaVolumeSpec.S3VolumeSource != nil {
-		bucket := path.Join(wp.Spec.MediaVolumeSpec.S3VolumeSource.Bucket, wp.Spec.MediaVolumeSpec.S3VolumeSource.PathPrefix)
+		bucket := path.Join(wp.Spec.MediaVolumeSpec.S3VolumeSource.Bucket, wp.s3PathPrefix())
 
 		out = append(out, corev1.EnvVar{
 			Name:  "STACK_MEDIA_BUCKET",
 			Value: fmt.Sprintf("%s://%s", s3Prefix, bucket),
 		})
 
+		if wp.Spec.MediaVolumeSpec.S3VolumeSource.Region != "" {
+			out = append(out, corev1.EnvVar{
+				Name:  "AWS_REGION",
+				Value: wp.Spec.MediaVolumeSpec.S3VolumeSource.Region,
+			})
+		}
+
+		if wp.Spec.MediaVolumeSpec.S3VolumeSource.ForcePathStyle {
+			out = append(out, corev1.EnvVar{
+				Name:  "S3_FORCE_PATH_STYLE",
+				Value: "true",
+			})
+		}
+
+		if wp.Spec.MediaVolumeSpec.S3VolumeSource.ObjectACL != "" {
+			out = append(out, corev1.EnvVar{
+				Name:  "STACK_MEDIA_ACL",
+				Value: wp.Spec.MediaVolumeSpec.S3VolumeSource.ObjectACL,
+			})
+		}
+
 		for _, env := range wp.Spec.MediaVolumeSpec.S3VolumeSource.Env {
 			if name, ok := s3EnvVars[env.Name]; ok {
 				_env := env.DeepCopy()
@@ -128,6 +322,13 @@ func (wp *Wordpress) mediaEnv() []corev1.EnvVar {
 			Value: fmt.Sprintf("%s://%s", gcsPrefix, bucket),
 		})
 
+		if wp.Spec.MediaVolumeSpec.GCSVolumeSource.PredefinedACL != "" {
+			out = append(out, corev1.EnvVar{
+				Name:  "STACK_MEDIA_ACL",
+				Value: wp.Spec.MediaVolumeSpec.GCSVolumeSource.PredefinedACL,
+			})
+		}
+
 		for _, env := range wp.Spec.MediaVolumeSpec.GCSVolumeSource.Env {
 			if name, ok := gcsEnvVars[env.Name]; ok {
 				_env := env.DeepCopy()
@@ -154,8 +355,11 @@ func (wp *Wordpress) routes() []string {
 	return out
 }
 
+// env returns the wordpress containers env. The computed vars come before the
+// user defined ones, so those can reference them using $(VAR_NAME) expansion.
+// The media vars come last, so the media path prefix can reference both.
 func (wp *Wordpress) env() []corev1.EnvVar {
-	out := append([]corev1.EnvVar{
+	out := []corev1.EnvVar{
 		{
 			Name:  "WP_HOME",
 			Value: wp.HomeURL(),
@@ -180,13 +384,109 @@ func (wp *Wordpress) env() []corev1.EnvVar {
 			Name:  "STACK_SITE_NAMESPACE",
 			Value: wp.Namespace,
 		},
-	}, wp.Spec.Env...)
+	}
 
+	out = append(out, wp.resourceLimitsEnv()...)
+	out = append(out, wp.memoryLimitEnv()...)
+	out = append(out, wp.sharedSessionEnv()...)
+	out = append(out, wp.debugEnv()...)
+	out = append(out, wp.vaultEnv()...)
+	out = append(out, wp.Spec.Env...)
 	out = append(out, wp.mediaEnv()...)
 
 	return out
 }
 
+// resourceLimitsEnv exposes the container limits through the downward API.
+// When a limit is not set, its value falls back to the node allocatable.
+func (wp *Wordpress) resourceLimitsEnv() []corev1.EnvVar {
+	if !wp.Spec.ExposeResourceLimits {
+		return nil
+	}
+
+	return []corev1.EnvVar{
+		{
+			Name: "CONTAINER_MEMORY_LIMIT",
+			ValueFrom: &corev1.EnvVarSource{
+				ResourceFieldRef: &corev1.ResourceFieldSelector{
+					Resource: "limits.memory",
+				},
+			},
+		},
+		{
+			Name: "CONTAINER_CPU_LIMIT",
+			ValueFrom: &corev1.EnvVarSource{
+				ResourceFieldRef: &corev1.ResourceFieldSelector{
+					Resource: "limits.cpu",
+				},
+			},
+		},
+	}
+}
+
+// memoryLimitEnv exposes the container memory limit to the runtime, if set.
+func (wp *Wordpress) memoryLimitEnv() []corev1.EnvVar {
+	if !wp.Spec.ExposeMemoryLimitEnv {
+		return nil
+	}
+
+	if _, ok := wp.resources().Limits[corev1.ResourceMemory]; !ok {
+		return nil
+	}
+
+	out := []corev1.EnvVar{}
+
+	for _, name := range []string{"GOMEMLIMIT", "RUNTIME_MEMORY_LIMIT"} {
+		out = append(out, corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				ResourceFieldRef: &corev1.ResourceFieldSelector{
+					Resource: "limits.memory",
+				},
+			},
+		})
+	}
+
+	return out
+}
+
+// sharedSessionEnv points the PHP session.save_path to the shared session volume.
+func (wp *Wordpress) sharedSessionEnv() []corev1.EnvVar {
+	if wp.Spec.SharedSessionVolume == nil {
+		return nil
+	}
+
+	return []corev1.EnvVar{
+		{
+			Name:  "PHP_SESSION_SAVE_PATH",
+			Value: wp.Spec.SharedSessionVolume.MountPath,
+		},
+	}
+}
+
+// debugEnv enables the WordPress debug mode, logging to the /var/log volume.
+func (wp *Wordpress) debugEnv() []corev1.EnvVar {
+	if wp.Spec.Debug == nil || !wp.Spec.Debug.Enabled {
+		return nil
+	}
+
+	return []corev1.EnvVar{
+		{
+			Name:  "WP_DEBUG",
+			Value: "true",
+		},
+		{
+			Name: "WP_DEBUG_LOG",
+			// cleaning the path as absolute keeps it within the volume
+			Value: path.Join(knativeVarLogMountPath, path.Clean("/"+wp.Spec.Debug.LogPath)),
+		},
+		{
+			Name:  "WP_DEBUG_DISPLAY",
+			Value: "false",
+		},
+	}
+}
+
 func (wp *Wordpress) envFrom() []corev1.EnvFromSource {
 	out := []corev1.EnvFromSource{
 		{
@@ -203,26 +503,138 @@ func (wp *Wordpress) envFrom() []corev1.EnvFromSource {
 	return out
 }
 
-func (wp *Wordpress) gitCloneEnv() []corev1.EnvVar {
-	if wp.Spec.CodeVolumeSpec.GitDir == nil {
-		return []corev1.EnvVar{}
-	}
+// ContainerEnv returns the environment variables passed to the wordpress runtime containers.
+func (wp *Wordpress) ContainerEnv() []corev1.EnvVar {
+	return wp.env()
+}
+
+// ContainerEnvFrom returns the envFrom sources passed to the wordpress runtime containers.
+func (wp *Wordpress) ContainerEnvFrom() []corev1.EnvFromSource {
+	return wp.envFrom()
+}
 
+// gitSourceEnv returns the git clone script env for cloning the given
+// repository reference into srcDir.
+func gitSourceEnv(repository, ref, srcDir string) []corev1.EnvVar {
 	out := []corev1.EnvVar{
 		{
 			Name:  "GIT_CLONE_URL",
-			Value: wp.Spec.CodeVolumeSpec.GitDir.Repository,
+			Value: repository,
 		},
 		{
 			Name:  "SRC_DIR",
-			Value: codeSrcMountPath,
+			Value: srcDir,
 		},
 	}
 
-	if len(wp.Spec.CodeVolumeSpec.GitDir.GitRef) > 0 {
+	if len(ref) > 0 {
 		out = append(out, corev1.EnvVar{
 			Name:  "GIT_CLONE_REF",
-			Value: wp.Spec.CodeVolumeSpec.GitDir.GitRef,
+			Value: ref,
+		})
+	}
+
+	return out
+}
+
+// setGitCommitAnnotation records the cloned git reference, since the commit
+// checked out for a branch or tag is only known at runtime.
+func (wp *Wordpress) setGitCommitAnnotation(out *corev1.PodTemplateSpec) {
+	if wp.Spec.CodeVolumeSpec == nil || wp.Spec.CodeVolumeSpec.GitDir == nil {
+		return
+	}
+
+	if out.Annotations == nil {
+		out.Annotations = make(map[string]string)
+	}
+
+	// no reference checks out the default branch
+	ref := wp.Spec.CodeVolumeSpec.GitDir.GitRef
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	out.Annotations[GitCommitAnnotation] = ref
+}
+
+func (wp *Wordpress) gitCloneEnv() []corev1.EnvVar {
+	if wp.Spec.CodeVolumeSpec.GitDir == nil {
+		return []corev1.EnvVar{}
+	}
+
+	out := gitSourceEnv(wp.Spec.CodeVolumeSpec.GitDir.Repository, wp.Spec.CodeVolumeSpec.GitDir.GitRef,
+		path.Join(codeSrcMountPath, wp.Spec.CodeVolumeSpec.GitDir.WorktreeSubdir))
+
+	if wp.hasGitSSHKeySecret() {
+		out = append(out, corev1.EnvVar{
+			Name:  "SSH_KEY_FILE",
+			Value: path.Join(gitSSHKeyMountPath, gitSSHKeyFileName),
+		})
+	}
+
+	if wp.hasGitCABundleSecret() {
+		out = append(out, corev1.EnvVar{
+			Name:  "GIT_SSL_CAINFO",
+			Value: path.Join(gitCABundleMountPath, gitCABundleFileName),
+		})
+	}
+
+	if wp.hasGitVerifySignature() {
+		out = append(out, corev1.EnvVar{
+			Name:  "GIT_VERIFY_KEY_FILE",
+			Value: path.Join(gitVerifyKeyMountPath, gitVerifyKeyFileName),
+		})
+	}
+
+	if wp.hasGitReferenceRepo() {
+		out = append(out, corev1.EnvVar{
+			Name:  "GIT_CLONE_REFERENCE",
+			Value: gitReferenceMountPath,
+		})
+	}
+
+	if wp.Spec.CodeVolumeSpec.GitDir.AtomicClone {
+		out = append(out, corev1.EnvVar{
+			Name:  "GIT_CLONE_ATOMIC",
+			Value: "true",
+		})
+	}
+
+	if wp.Spec.CodeVolumeSpec.GitDir.SkipIfPopulated {
+		out = append(out, corev1.EnvVar{
+			Name:  "GIT_CLONE_SKIP_IF_POPULATED",
+			Value: "true",
+		})
+	}
+
+	if reclone, ok := wp.Annotations[ForceRecloneAnnotation]; ok {
+		out = append(out, corev1.EnvVar{
+			Name:  "GIT_CLONE_RECLONE",
+			Value: reclone,
+		})
+	}
+
+	if wp.Spec.CodeVolumeSpec.GitDir.MountMedia && wp.hasMediaMounts() {
+		out = append(out, corev1.EnvVar{
+			Name:  "MEDIA_DIR",
+			Value: gitMediaMountPath,
+		})
+	}
+
+	if wp.Spec.CodeVolumeSpec.GitDir.Mirror {
+		out = append(out, corev1.EnvVar{
+			Name:  "GIT_CLONE_MIRROR",
+			Value: "true",
+		}, corev1.EnvVar{
+			Name:  "GIT_MIRROR_DIR",
+			Value: path.Join(codeSrcMountPath, gitMirrorSubdir),
+		})
+	}
+
+	if wp.Spec.CodeVolumeSpec.GitDir.Detached {
+		out = append(out, corev1.EnvVar{
+			Name:  "GIT_CLONE_DETACHED",
+			Value: "true",
 		})
 	}
 
@@ -239,24 +651,72 @@ func (wp *Wordpress) volumeMounts() []corev1.VolumeMount {
 		},
 	}
 	out = append(out, wp.Spec.VolumeMounts...)
+	out = append(out, wp.fileVolumeMounts()...)
+
+	if wp.Spec.SharedSocketVolume != nil {
+		out = append(out, wp.sharedSocketVolumeMount())
+	}
+
+	if wp.Spec.SharedSessionVolume != nil {
+		out = append(out, corev1.VolumeMount{
+			MountPath: wp.Spec.SharedSessionVolume.MountPath,
+			Name:      sharedSessionVolume,
+		})
+	}
+
+	if wp.Spec.VaultAgent != nil {
+		out = append(out, wp.vaultSecretsVolumeMount())
+	}
+
+	if wp.hasMediaToken() {
+		out = append(out, corev1.VolumeMount{
+			MountPath: mediaTokenMountPath,
+			Name:      mediaTokenVolume,
+			ReadOnly:  true,
+		})
+	}
+
+	if wp.hasMediaPreSync() {
+		out = append(out, wp.mediaCacheVolumeMount())
+	}
+
+	if wp.Spec.ExposePodInfo {
+		out = append(out, corev1.VolumeMount{
+			MountPath: podInfoMountPath,
+			Name:      podInfoVolume,
+			ReadOnly:  true,
+		})
+	}
 
 	if wp.hasCodeMounts() {
 		out = append(out, corev1.VolumeMount{
 			MountPath: codeSrcMountPath,
 			Name:      codeVolumeName,
 			ReadOnly:  wp.Spec.CodeVolumeSpec.ReadOnly,
+			SubPath:   wp.codeSubPath(""),
 		})
 		out = append(out, corev1.VolumeMount{
 			MountPath: wp.Spec.CodeVolumeSpec.MountPath,
 			Name:      codeVolumeName,
 			ReadOnly:  wp.Spec.CodeVolumeSpec.ReadOnly,
-			SubPath:   wp.Spec.CodeVolumeSpec.ContentSubPath,
+			SubPath:   wp.codeSubPath(wp.Spec.CodeVolumeSpec.ContentSubPath),
 		})
+
+		if !wp.hasConfigGitDir() {
+			out = append(out, corev1.VolumeMount{
+				MountPath: configMountPath,
+				Name:      codeVolumeName,
+				ReadOnly:  true,
+				SubPath:   wp.codeSubPath(wp.Spec.CodeVolumeSpec.ConfigSubPath),
+			})
+		}
+	}
+
+	if wp.hasConfigGitDir() {
 		out = append(out, corev1.VolumeMount{
 			MountPath: configMountPath,
-			Name:      codeVolumeName,
+			Name:      configVolumeName,
 			ReadOnly:  true,
-			SubPath:   wp.Spec.CodeVolumeSpec.ConfigSubPath,
 		})
 	}
 
@@ -277,11 +737,21 @@ func (wp *Wordpress) volumeMounts() []corev1.VolumeMount {
 	return out
 }
 
+// codeSubPath returns the given path within the code volume, relative to
+// the git worktree directory, if one is set.
+func (wp *Wordpress) codeSubPath(subPath string) string {
+	if wp.Spec.CodeVolumeSpec.GitDir == nil || wp.Spec.CodeVolumeSpec.GitDir.WorktreeSubdir == "" {
+		return subPath
+	}
+
+	return path.Join(wp.Spec.CodeVolumeSpec.GitDir.WorktreeSubdir, subPath)
+}
+
 func (wp *Wordpress) codeVolume() corev1.Volume {
 	codeVolume := corev1.Volume{
 		Name: codeVolumeName,
 		VolumeSource: corev1.VolumeSource{
-			EmptyDir: &corev1.EmptyDirVolumeSource{},
+			EmptyDir: wp.defaultEmptyDir(),
 		},
 	}
 
@@ -289,7 +759,12 @@ func (wp *Wordpress) codeVolume() corev1.Volume {
 		switch {
 		case wp.Spec.CodeVolumeSpec.GitDir != nil:
 			if wp.Spec.CodeVolumeSpec.GitDir.EmptyDir != nil {
-				codeVolume.EmptyDir = wp.Spec.CodeVolumeSpec.GitDir.EmptyDir
+				codeVolume.EmptyDir = wp.Spec.CodeVolumeSpec.GitDir.EmptyDir.DeepCopy()
+			}
+
+			if wp.Spec.CodeVolumeSpec.GitDir.SizeLimit != nil {
+				sizeLimit := wp.Spec.CodeVolumeSpec.GitDir.SizeLimit.DeepCopy()
+				codeVolume.EmptyDir.SizeLimit = &sizeLimit
 			}
 		case wp.Spec.CodeVolumeSpec.PersistentVolumeClaim != nil:
 			codeVolume = corev1.Volume{
@@ -315,11 +790,44 @@ func (wp *Wordpress) codeVolume() corev1.Volume {
 	return codeVolume
 }
 
+// mediaClaimName returns the name of the media claim. In StatefulSet mode,
+// that is the claim of the first replica.
+func (wp *Wordpress) mediaClaimName() string {
+	if wp.hasMediaClaimTemplate() {
+		return fmt.Sprintf("%s-%s-0", mediaVolumeName, wp.ComponentName(WordpressStatefulSet))
+	}
+
+	return wp.ComponentName(WordpressMediaPVC)
+}
+
+func (wp *Wordpress) hasMediaClaimTemplate() bool {
+	return wp.Spec.StatefulSetMode && wp.Spec.MediaVolumeSpec != nil && wp.Spec.MediaVolumeSpec.PersistentVolumeClaim != nil
+}
+
+// MediaVolumeClaimTemplates returns the StatefulSet claim templates, giving
+// each replica its own media claim.
+func (wp *Wordpress) MediaVolumeClaimTemplates() []corev1.PersistentVolumeClaim {
+	if !wp.hasMediaClaimTemplate() {
+		return nil
+	}
+
+	return []corev1.PersistentVolumeClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        mediaVolumeName,
+				Labels:      wp.Spec.MediaVolumeSpec.Labels,
+				Annotations: wp.Spec.MediaVolumeSpec.Annotations,
+			},
+			Spec: *wp.Spec.MediaVolumeSpec.PersistentVolumeClaim,
+		},
+	}
+}
+
 func (wp *Wordpress) mediaVolume() corev1.Volume {
 	mediaVolume := corev1.Volume{
 		Name: mediaVolumeName,
 		VolumeSource: corev1.VolumeSource{
-			EmptyDir: &corev1.EmptyDirVolumeSource{},
+			EmptyDir: wp.defaultEmptyDir(),
 		},
 	}
 
@@ -330,10 +838,24 @@ func (wp *Wordpress) mediaVolume() corev1.Volume {
 				Name: mediaVolumeName,
 				VolumeSource: corev1.VolumeSource{
 					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-						ClaimName: wp.ComponentName(WordpressMediaPVC),
+						ClaimName: wp.mediaClaimName(),
 					},
 				},
 			}
+		case wp.Spec.MediaVolumeSpec.CephFS != nil:
+			mediaVolume = corev1.Volume{
+				Name: mediaVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					CephFS: wp.Spec.MediaVolumeSpec.CephFS,
+				},
+			}
+		case wp.Spec.MediaVolumeSpec.Glusterfs != nil:
+			mediaVolume = corev1.Volume{
+				Name: mediaVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					Glusterfs: wp.Spec.MediaVolumeSpec.Glusterfs,
+				},
+			}
 		case wp.Spec.MediaVolumeSpec.HostPath != nil:
 			mediaVolume = corev1.Volume{
 				Name: mediaVolumeName,
@@ -349,77 +871,658 @@ func (wp *Wordpress) mediaVolume() corev1.Volume {
 	return mediaVolume
 }
 
-func (wp *Wordpress) volumes() []corev1.Volume {
-	volumes := []corev1.Volume{
-		{
-			Name: knativeInternalVolume,
-			VolumeSource: corev1.VolumeSource{
-				EmptyDir: &corev1.EmptyDirVolumeSource{},
-			},
-		},
-		{
-			Name: knativeVarLogVolume,
-			VolumeSource: corev1.VolumeSource{
-				EmptyDir: &corev1.EmptyDirVolumeSource{
-					SizeLimit: &varLogSizeLimit,
-				},
-			},
-		},
-	}
-	volumes = append(volumes, wp.Spec.Volumes...)
-
-	if wp.hasCodeMounts() {
-		volumes = append(volumes, wp.codeVolume())
-	}
+// defaultEmptyDir returns the emptyDir used when no code or media volume
+// source is specified.
+func (wp *Wordpress) defaultEmptyDir() *corev1.EmptyDirVolumeSource {
+	emptyDir := &corev1.EmptyDirVolumeSource{}
 
-	if wp.hasMediaMounts() {
-		volumes = append(volumes, wp.mediaVolume())
+	if wp.Spec.DefaultVolumeSizeLimit != nil {
+		sizeLimit := wp.Spec.DefaultVolumeSizeLimit.DeepCopy()
+		emptyDir.SizeLimit = &sizeLimit
 	}
 
-	return volumes
+	return emptyDir
 }
 
-func (wp *Wordpress) securityContext() *corev1.SecurityContext {
-	defaultProcMount := corev1.DefaultProcMount
+// mediaTokenVolume projects a service account token for authenticating to the
+// media object storage.
+func (wp *Wordpress) mediaTokenVolume() corev1.Volume {
+	var expirationSeconds int64 = 3600
 
-	return &corev1.SecurityContext{
-		RunAsUser: &wwwDataUserID,
-		ProcMount: &defaultProcMount,
+	return corev1.Volume{
+		Name: mediaTokenVolume,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{
+					{
+						ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+							Audience:          wp.Spec.MediaVolumeSpec.TokenAudience,
+							ExpirationSeconds: &expirationSeconds,
+							Path:              mediaTokenFileName,
+						},
+					},
+				},
+			},
+		},
 	}
 }
 
-func (wp *Wordpress) gitCloneContainer() corev1.Container {
-	return corev1.Container{
-		Name:    "git",
-		Args:    []string{"/bin/bash", "-c", gitCloneScript},
-		Image:   options.GitCloneImage,
-		Env:     wp.gitCloneEnv(),
-		EnvFrom: wp.Spec.CodeVolumeSpec.GitDir.EnvFrom,
-		VolumeMounts: []corev1.VolumeMount{
-			{
-				Name:      codeVolumeName,
-				MountPath: codeSrcMountPath,
+func (wp *Wordpress) podInfoVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: podInfoVolume,
+		VolumeSource: corev1.VolumeSource{
+			DownwardAPI: &corev1.DownwardAPIVolumeSource{
+				Items: []corev1.DownwardAPIVolumeFile{
+					{
+						Path: "labels",
+						FieldRef: &corev1.ObjectFieldSelector{
+							FieldPath: "metadata.labels",
+						},
+					},
+					{
+						Path: "annotations",
+						FieldRef: &corev1.ObjectFieldSelector{
+							FieldPath: "metadata.annotations",
+						},
+					},
+				},
 			},
 		},
-		SecurityContext: wp.securityContext(),
 	}
 }
 
-// nolint: funlen
-func (wp *Wordpress) prepareVolumesContainer() corev1.Container {
+func (wp *Wordpress) gitSSHKeyVolume() corev1.Volume {
+	var mode int32 = 0400
+
+	return corev1.Volume{
+		Name: gitSSHKeyVolume,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: wp.Spec.CodeVolumeSpec.GitDir.SSHKeySecret.Name,
+				Items: []corev1.KeyToPath{
+					{
+						Key:  wp.Spec.CodeVolumeSpec.GitDir.SSHKeySecret.Key,
+						Path: gitSSHKeyFileName,
+						Mode: &mode,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (wp *Wordpress) gitCABundleVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: gitCABundleVolume,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: wp.Spec.CodeVolumeSpec.GitDir.CABundleSecret.Name,
+				Items: []corev1.KeyToPath{
+					{
+						Key:  wp.Spec.CodeVolumeSpec.GitDir.CABundleSecret.Key,
+						Path: gitCABundleFileName,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (wp *Wordpress) gitVerifyKeyVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: gitVerifyKeyVolume,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: wp.Spec.CodeVolumeSpec.GitDir.VerifySignature.PublicKeySecret.Name,
+				Items: []corev1.KeyToPath{
+					{
+						Key:  wp.Spec.CodeVolumeSpec.GitDir.VerifySignature.PublicKeySecret.Key,
+						Path: gitVerifyKeyFileName,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (wp *Wordpress) gitReferenceVolume() corev1.Volume {
+	// a missing directory gets created, in which case the clone falls back to not using a reference
+	hostPathType := corev1.HostPathDirectoryOrCreate
+
+	return corev1.Volume{
+		Name: gitReferenceVolume,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: wp.Spec.CodeVolumeSpec.GitDir.ReferenceRepoPath,
+				Type: &hostPathType,
+			},
+		},
+	}
+}
+
+func (wp *Wordpress) volumes() []corev1.Volume {
+	volumes := []corev1.Volume{
+		{
+			Name: knativeInternalVolume,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+		{
+			Name: knativeVarLogVolume,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{
+					Medium:    wp.Spec.LogVolumeMedium,
+					SizeLimit: &varLogSizeLimit,
+				},
+			},
+		},
+	}
+	volumes = append(volumes, wp.Spec.Volumes...)
+	volumes = append(volumes, wp.fileVolumes()...)
+
+	if wp.Spec.ExposePodInfo {
+		volumes = append(volumes, wp.podInfoVolume())
+	}
+
+	if wp.Spec.SharedSocketVolume != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: sharedSocketVolume,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+	}
+
+	if wp.Spec.SharedSessionVolume != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: sharedSessionVolume,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: wp.Spec.SharedSessionVolume.PersistentVolumeClaim,
+				NFS:                   wp.Spec.SharedSessionVolume.NFS,
+			},
+		})
+	}
+
+	if wp.Spec.VaultAgent != nil {
+		volumes = append(volumes, wp.vaultVolumes()...)
+	}
+
+	if wp.hasMediaToken() {
+		volumes = append(volumes, wp.mediaTokenVolume())
+	}
+
+	if wp.hasMediaPreSync() {
+		volumes = append(volumes, wp.mediaCacheVolume())
+	}
+
+	if wp.hasCodeMounts() {
+		volumes = append(volumes, wp.codeVolume())
+	}
+
+	if wp.hasMediaMounts() {
+		volumes = append(volumes, wp.mediaVolume())
+	}
+
+	if wp.hasConfigGitDir() {
+		volumes = append(volumes, corev1.Volume{
+			Name: configVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+	}
+
+	if wp.hasGitSSHKeySecret() {
+		volumes = append(volumes, wp.gitSSHKeyVolume())
+	}
+
+	if wp.hasGitCABundleSecret() {
+		volumes = append(volumes, wp.gitCABundleVolume())
+	}
+
+	if wp.hasGitVerifySignature() {
+		volumes = append(volumes, wp.gitVerifyKeyVolume())
+	}
+
+	if wp.hasGitReferenceRepo() {
+		volumes = append(volumes, wp.gitReferenceVolume())
+	}
+
+	return volumes
+}
+
+// ValidateSecurityProfile checks that the restricted profile isn't combined
+// with the Chown volume permissions mode, whose init container runs as root.
+func (wp *Wordpress) ValidateSecurityProfile() error {
+	if wp.Spec.SecurityProfile == wordpressv1alpha1.SecurityProfileRestricted &&
+		wp.Spec.VolumePermissionsMode == wordpressv1alpha1.VolumePermissionsChown {
+		return fmt.Errorf("%w: the restricted profile requires the FSGroup volume permissions mode", errSecurityProfile)
+	}
+
+	return nil
+}
+
+func (wp *Wordpress) securityContext() *corev1.SecurityContext {
+	defaultProcMount := corev1.DefaultProcMount
+
+	out := &corev1.SecurityContext{
+		RunAsUser:  &wwwDataUserID,
+		RunAsGroup: wp.runAsGroup(),
+		ProcMount:  &defaultProcMount,
+	}
+
+	switch wp.Spec.SecurityProfile {
+	case wordpressv1alpha1.SecurityProfileRestricted:
+		runAsNonRoot := true
+		out.RunAsNonRoot = &runAsNonRoot
+		out.Capabilities = &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		}
+
+		fallthrough
+	case wordpressv1alpha1.SecurityProfileBaseline:
+		allowPrivilegeEscalation := false
+		out.AllowPrivilegeEscalation = &allowPrivilegeEscalation
+		out.SeccompProfile = &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		}
+	case wordpressv1alpha1.SecurityProfilePrivilegedLegacy:
+	}
+
+	if wp.Spec.SecurityContext != nil {
+		// nolint: errcheck
+		mergo.Merge(out, wp.Spec.SecurityContext.DeepCopy(), mergo.WithOverride)
+	}
+
+	return out
+}
+
+func (wp *Wordpress) runAsGroup() *int64 {
+	if wp.Spec.RunAsGroup != nil {
+		return wp.Spec.RunAsGroup
+	}
+
+	return &wwwDataGroupID
+}
+
+func (wp *Wordpress) sidecarSecurityContext() *corev1.SecurityContext {
+	runAsNonRoot := true
+	allowPrivilegeEscalation := false
+
+	return &corev1.SecurityContext{
+		RunAsNonRoot:             &runAsNonRoot,
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+	}
+}
+
+func (wp *Wordpress) sidecars() []corev1.Container {
+	if !wp.Spec.HardenSidecars && wp.Spec.SharedSocketVolume == nil && wp.Spec.ShutdownOrder == nil {
+		return wp.Spec.Sidecars
+	}
+
+	out := make([]corev1.Container, len(wp.Spec.Sidecars))
+
+	for i := range wp.Spec.Sidecars {
+		wp.Spec.Sidecars[i].DeepCopyInto(&out[i])
+
+		if wp.Spec.HardenSidecars && out[i].SecurityContext == nil {
+			out[i].SecurityContext = wp.sidecarSecurityContext()
+		}
+
+		if wp.Spec.SharedSocketVolume != nil {
+			out[i].VolumeMounts = append(out[i].VolumeMounts, wp.sharedSocketVolumeMount())
+		}
+
+		if wp.Spec.ShutdownOrder != nil && (out[i].Lifecycle == nil || out[i].Lifecycle.PreStop == nil) {
+			if out[i].Lifecycle == nil {
+				out[i].Lifecycle = &corev1.Lifecycle{}
+			}
+
+			out[i].Lifecycle.PreStop = &corev1.Handler{
+				Exec: &corev1.ExecAction{
+					Command: []string{"sleep", fmt.Sprintf("%d", wp.Spec.DrainSeconds+wp.sidecarsShutdownDelay())},
+				},
+			}
+		}
+	}
+
+	return out
+}
+
+// sidecarsShutdownDelay returns the seconds the sidecars wait before stopping,
+// after the wordpress container drained.
+func (wp *Wordpress) sidecarsShutdownDelay() int32 {
+	if wp.Spec.ShutdownOrder == nil || wp.Spec.ShutdownOrder.SidecarsDelaySeconds == nil {
+		return 0
+	}
+
+	return *wp.Spec.ShutdownOrder.SidecarsDelaySeconds
+}
+
+// wordpressShutdownDelay returns the seconds the wordpress container waits
+// before stopping, after draining, so it stops after the sidecars.
+func (wp *Wordpress) wordpressShutdownDelay() int32 {
+	if wp.Spec.ShutdownOrder == nil || wp.Spec.ShutdownOrder.WordpressDelaySeconds == nil {
+		return wp.sidecarsShutdownDelay()
+	}
+
+	return wp.sidecarsShutdownDelay() + *wp.Spec.ShutdownOrder.WordpressDelaySeconds
+}
+
+func (wp *Wordpress) sharedSocketVolumeMount() corev1.VolumeMount {
+	return corev1.VolumeMount{
+		Name:      sharedSocketVolume,
+		MountPath: wp.Spec.SharedSocketVolume.MountPath,
+	}
+}
+
+func (wp *Wordpress) gitCloneContainer() corev1.Container {
+	c := corev1.Container{
+		Name:            "git",
+		Args:            []string{"/bin/bash", "-c", gitCloneScript},
+		Image:           options.GitCloneImage,
+		ImagePullPolicy: wp.Spec.InitImagePullPolicy,
+		Env:             wp.gitCloneEnv(),
+		EnvFrom:         wp.Spec.CodeVolumeSpec.GitDir.EnvFrom,
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      codeVolumeName,
+				MountPath: codeSrcMountPath,
+			},
+		},
+		SecurityContext:          wp.securityContext(),
+		TerminationMessagePolicy: wp.Spec.TerminationMessagePolicy,
+	}
+
+	if len(wp.Spec.CodeVolumeSpec.GitDir.PostCloneCommand) > 0 {
+		// the script name ($0) comes before the post-clone command arguments
+		c.Args = append(c.Args, "git-clone")
+		c.Args = append(c.Args, wp.Spec.CodeVolumeSpec.GitDir.PostCloneCommand...)
+	}
+
+	if wp.hasGitSSHKeySecret() {
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      gitSSHKeyVolume,
+			MountPath: gitSSHKeyMountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	if wp.hasGitCABundleSecret() {
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      gitCABundleVolume,
+			MountPath: gitCABundleMountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	if wp.hasGitVerifySignature() {
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      gitVerifyKeyVolume,
+			MountPath: gitVerifyKeyMountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	if wp.hasGitReferenceRepo() {
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      gitReferenceVolume,
+			MountPath: gitReferenceMountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	if wp.Spec.CodeVolumeSpec.GitDir.MountMedia && wp.hasMediaMounts() {
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      mediaVolumeName,
+			MountPath: gitMediaMountPath,
+			SubPath:   wp.Spec.MediaVolumeSpec.ContentSubPath,
+		})
+	}
+
+	return c
+}
+
+func (wp *Wordpress) configGitCloneContainer() corev1.Container {
+	env := gitSourceEnv(wp.Spec.CodeVolumeSpec.ConfigGitDir.Repository, wp.Spec.CodeVolumeSpec.ConfigGitDir.GitRef,
+		configSrcMountPath)
+
+	return corev1.Container{
+		Name:            "git-config",
+		Args:            []string{"/bin/bash", "-c", gitCloneScript},
+		Image:           options.GitCloneImage,
+		ImagePullPolicy: wp.Spec.InitImagePullPolicy,
+		Env:             append(env, wp.Spec.CodeVolumeSpec.ConfigGitDir.Env...),
+		EnvFrom:         wp.Spec.CodeVolumeSpec.ConfigGitDir.EnvFrom,
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      configVolumeName,
+				MountPath: configSrcMountPath,
+			},
+		},
+		SecurityContext:          wp.securityContext(),
+		TerminationMessagePolicy: wp.Spec.TerminationMessagePolicy,
+	}
+}
+
+// ValidateCodeVolume checks that the git repository schemes are allowed by
+// the operator, that the config repository mount doesn't overlap the code
+// mount and that the media volume can be mounted read-write in the git clone
+// container, if requested.
+func (wp *Wordpress) ValidateCodeVolume() error {
+	if wp.Spec.CodeVolumeSpec == nil {
+		return nil
+	}
+
+	if wp.hasConfigGitDir() {
+		if err := validateGitScheme(wp.Spec.CodeVolumeSpec.ConfigGitDir.Repository); err != nil {
+			return err
+		}
+
+		p := path.Clean(wp.Spec.CodeVolumeSpec.MountPath)
+		if p == configMountPath || strings.HasPrefix(p, configMountPath+"/") || strings.HasPrefix(configMountPath, p+"/") {
+			return fmt.Errorf("%w: %s overlaps the config mount %s", errConfigMountPath, wp.Spec.CodeVolumeSpec.MountPath, configMountPath)
+		}
+	}
+
+	if wp.Spec.CodeVolumeSpec.GitDir == nil {
+		return nil
+	}
+
+	if err := validateGitScheme(wp.Spec.CodeVolumeSpec.GitDir.Repository); err != nil {
+		return err
+	}
+
+	if !wp.Spec.CodeVolumeSpec.GitDir.MountMedia {
+		return nil
+	}
+
+	if !wp.hasMediaMounts() {
+		return fmt.Errorf("%w: .spec.media doesn't define a volume", errGitMediaMount)
+	}
+
+	if wp.isMediaReadOnly() {
+		return fmt.Errorf("%w: the media volume is read-only", errGitMediaMount)
+	}
+
+	return nil
+}
+
+// ValidateMediaVolume checks that the media files are either offloaded to a
+// single object storage or kept on a locally mounted volume.
+func (wp *Wordpress) ValidateMediaVolume() error {
+	if wp.Spec.MediaVolumeSpec == nil {
+		return nil
+	}
+
+	sources := []string{}
+
+	if wp.Spec.MediaVolumeSpec.S3VolumeSource != nil {
+		sources = append(sources, ".spec.media.s3")
+	}
+
+	if wp.Spec.MediaVolumeSpec.GCSVolumeSource != nil {
+		sources = append(sources, ".spec.media.gcs")
+	}
+
+	if len(sources) > 0 && wp.hasMediaMounts() {
+		sources = append(sources, fmt.Sprintf(".spec.media.%s", wp.mediaVolumeSourceName()))
+	}
+
+	if len(sources) > 1 {
+		return fmt.Errorf("%w: %s can't be set together", errConflictingMedia, strings.Join(sources, " and "))
+	}
+
+	if (wp.Spec.MediaVolumeSpec.TokenAudience == "") != (wp.Spec.MediaVolumeSpec.RoleARN == "") {
+		return fmt.Errorf("%w: .spec.media.tokenAudience and .spec.media.roleARN must be set together", errInvalidMediaToken)
+	}
+
+	if wp.Spec.MediaVolumeSpec.S3VolumeSource != nil {
+		for _, segment := range strings.Split(wp.s3PathPrefix(), "/") {
+			if segment == ".." {
+				return fmt.Errorf("%w: %s", errInvalidMediaPrefix, wp.s3PathPrefix())
+			}
+		}
+	}
+
+	if wp.hasMediaPreSync() {
+		if wp.Spec.MediaVolumeSpec.S3VolumeSource == nil && wp.Spec.MediaVolumeSpec.GCSVolumeSource == nil {
+			return fmt.Errorf("%w: .spec.media.preSync requires .spec.media.s3 or .spec.media.gcs", errMediaPreSync)
+		}
+
+		for _, segment := range strings.Split(wp.Spec.MediaVolumeSpec.PreSync.Prefix, "/") {
+			if segment == ".." {
+				return fmt.Errorf("%w: invalid prefix %s", errMediaPreSync, wp.Spec.MediaVolumeSpec.PreSync.Prefix)
+			}
+		}
+	}
+
+	return nil
+}
+
+// s3PathPrefix returns the prefix of the media files in the S3 bucket.
+func (wp *Wordpress) s3PathPrefix() string {
+	s3 := wp.Spec.MediaVolumeSpec.S3VolumeSource
+	if s3.PathPrefix == "" && s3.SitePathPrefix {
+		return path.Join(wp.Namespace, wp.Name)
+	}
+
+	return s3.PathPrefix
+}
+
+// mediaVolumeSourceName returns the name of the media volume source field,
+// in the mediaVolume precedence order.
+func (wp *Wordpress) mediaVolumeSourceName() string {
+	switch {
+	case wp.Spec.MediaVolumeSpec.PersistentVolumeClaim != nil:
+		return "persistentVolumeClaim"
+	case wp.Spec.MediaVolumeSpec.CephFS != nil:
+		return "cephfs"
+	case wp.Spec.MediaVolumeSpec.Glusterfs != nil:
+		return "glusterfs"
+	case wp.Spec.MediaVolumeSpec.HostPath != nil:
+		return "hostPath"
+	default:
+		return "emptyDir"
+	}
+}
+
+// ValidateSharedSocketVolume checks that the shared socket volume isn't
+// mounted over the operator managed mounts.
+func (wp *Wordpress) ValidateSharedSocketVolume() error {
+	if wp.Spec.SharedSocketVolume == nil {
+		return nil
+	}
+
+	return validateMountPath(wp.Spec.SharedSocketVolume.MountPath, wp.reservedMountPaths(), errSharedSocketPath)
+}
+
+// ValidateSharedSessionVolume checks that the shared session volume has a
+// single source and isn't mounted over the other mounts.
+func (wp *Wordpress) ValidateSharedSessionVolume() error {
+	if wp.Spec.SharedSessionVolume == nil {
+		return nil
+	}
+
+	if (wp.Spec.SharedSessionVolume.PersistentVolumeClaim == nil) == (wp.Spec.SharedSessionVolume.NFS == nil) {
+		return fmt.Errorf("%w: exactly one of persistentVolumeClaim and nfs must be set", errSharedSessionVolume)
+	}
+
+	reserved := wp.reservedMountPaths()
+	if wp.Spec.SharedSocketVolume != nil {
+		reserved = append(reserved, wp.Spec.SharedSocketVolume.MountPath)
+	}
+
+	return validateMountPath(wp.Spec.SharedSessionVolume.MountPath, reserved, errSharedSessionVolume)
+}
+
+// validateMountPath checks that the mount path is absolute and doesn't
+// collide with the reserved mount paths in either direction.
+func validateMountPath(mountPath string, reserved []string, sentinel error) error {
+	p := path.Clean(mountPath)
+	if !path.IsAbs(p) {
+		return fmt.Errorf("%w: %s is not absolute", sentinel, mountPath)
+	}
+
+	for _, r := range reserved {
+		if p == r || strings.HasPrefix(p, r+"/") || strings.HasPrefix(r, p+"/") {
+			return fmt.Errorf("%w: %s collides with reserved mount %s", sentinel, mountPath, r)
+		}
+	}
+
+	return nil
+}
+
+func validateGitScheme(repository string) error {
+	if len(options.AllowedGitSchemes) == 0 {
+		return nil
+	}
+
+	scheme := gitRepositoryScheme(repository)
+
+	for _, allowed := range options.AllowedGitSchemes {
+		if strings.EqualFold(allowed, scheme) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s (allowed: %s)", errGitSchemeNotAllowed, scheme, strings.Join(options.AllowedGitSchemes, ", "))
+}
+
+// gitRepositoryScheme returns the scheme of a git repository URL. The scp-like
+// syntax (eg. git@github.com:bitpoke/wordpress.git) is ssh and local paths are file.
+func gitRepositoryScheme(repository string) string {
+	if i := strings.Index(repository, "://"); i > 0 {
+		return strings.ToLower(repository[:i])
+	}
+
+	if i := strings.Index(repository, ":"); i > 0 && !strings.Contains(repository[:i], "/") {
+		return "ssh"
+	}
+
+	return "file"
+}
+
+// nolint: funlen
+func (wp *Wordpress) prepareVolumesContainer() corev1.Container {
 	var script bytes.Buffer
 
 	// nolint: errcheck
-	prepareVolumesScriptTemplate.Execute(&script, map[string]string{
+	prepareVolumesScriptTemplate.Execute(&script, map[string]interface{}{
 		"wwwDataUserID":      fmt.Sprintf("%d", wwwDataUserID),
+		"runAsGroup":         fmt.Sprintf("%d", *wp.runAsGroup()),
 		"knativeInternalDir": knativeInternalMountPath,
 		"knativeVarLogDir":   knativeVarLogMountPath,
+		"chown":              !wp.usesFSGroupPermissions(),
 	})
 
 	c := corev1.Container{
-		Name:  "prepare-volumes",
-		Args:  []string{"/bin/sh", "-c", script.String()},
-		Image: prepareVolumesImage,
+		Name:                     "prepare-volumes",
+		Args:                     []string{"/bin/sh", "-c", script.String()},
+		Image:                    prepareVolumesImage,
+		ImagePullPolicy:          wp.Spec.InitImagePullPolicy,
+		TerminationMessagePolicy: wp.Spec.TerminationMessagePolicy,
 		VolumeMounts: []corev1.VolumeMount{
 			{
 				Name:      knativeInternalVolume,
@@ -450,6 +1553,13 @@ func (wp *Wordpress) prepareVolumesContainer() corev1.Container {
 		},
 	}
 
+	// with fsGroup, only the log link is created, as the wordpress user
+	if wp.usesFSGroupPermissions() {
+		c.SecurityContext = wp.securityContext()
+
+		return c
+	}
+
 	if wp.hasCodeMounts() && !wp.Spec.CodeVolumeSpec.ReadOnly {
 		m := corev1.VolumeMount{
 			Name:      codeVolumeName,
@@ -457,13 +1567,13 @@ func (wp *Wordpress) prepareVolumesContainer() corev1.Container {
 		}
 
 		if wp.Wordpress.Spec.CodeVolumeSpec.ContentSubPath != "" {
-			m.SubPath = wp.Wordpress.Spec.CodeVolumeSpec.ContentSubPath
+			m.SubPath = wp.codeSubPath(wp.Wordpress.Spec.CodeVolumeSpec.ContentSubPath)
 		}
 
 		c.VolumeMounts = append(c.VolumeMounts, m)
 	}
 
-	if wp.hasMediaMounts() && !wp.Spec.MediaVolumeSpec.ReadOnly {
+	if wp.hasMediaMounts() && !wp.isMediaReadOnly() {
 		m := corev1.VolumeMount{
 			Name:      mediaVolumeName,
 			MountPath: "/mnt/media",
@@ -473,54 +1583,224 @@ func (wp *Wordpress) prepareVolumesContainer() corev1.Container {
 			m.SubPath = wp.Wordpress.Spec.MediaVolumeSpec.ContentSubPath
 		}
 
-		c.VolumeMounts = append(c.VolumeMounts, m)
+		c.VolumeMounts = append(c.VolumeMounts, m)
+	}
+
+	return c
+}
+
+// usesFSGroupPermissions returns true if the volumes ownership is set through
+// the pod fsGroup instead of the prepare-volumes container.
+func (wp *Wordpress) usesFSGroupPermissions() bool {
+	return wp.Spec.VolumePermissionsMode == wordpressv1alpha1.VolumePermissionsFSGroup
+}
+
+func (wp *Wordpress) installWPEnv() []corev1.EnvVar {
+	attempts := wp.Spec.WordpressBootstrapSpec.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var retryDelay int32
+	if wp.Spec.WordpressBootstrapSpec.RetryDelaySeconds != nil {
+		retryDelay = *wp.Spec.WordpressBootstrapSpec.RetryDelaySeconds
+	}
+
+	out := append(wp.env(), wp.Spec.WordpressBootstrapSpec.Env...)
+
+	return append(out,
+		corev1.EnvVar{
+			Name:  "WORDPRESS_BOOTSTRAP_ATTEMPTS",
+			Value: fmt.Sprintf("%d", attempts),
+		},
+		corev1.EnvVar{
+			Name:  "WORDPRESS_BOOTSTRAP_RETRY_DELAY",
+			Value: fmt.Sprintf("%d", retryDelay),
+		},
+	)
+}
+
+func (wp *Wordpress) installWPContainer() []corev1.Container {
+	if wp.Spec.WordpressBootstrapSpec == nil {
+		return []corev1.Container{}
+	}
+
+	return []corev1.Container{
+		{
+			Name:                     "install-wp",
+			Image:                    wp.image(),
+			VolumeMounts:             wp.volumeMounts(),
+			Env:                      wp.installWPEnv(),
+			EnvFrom:                  append(wp.envFrom(), wp.Spec.WordpressBootstrapSpec.EnvFrom...),
+			SecurityContext:          wp.securityContext(),
+			Command:                  []string{"/bin/sh", "-c", installWPScript, "wp-install"},
+			TerminationMessagePolicy: wp.Spec.TerminationMessagePolicy,
+			Args: []string{
+				"$(WORDPRESS_BOOTSTRAP_TITLE)",
+				wp.HomeURL(),
+				"$(WORDPRESS_BOOTSTRAP_USER)",
+				"$(WORDPRESS_BOOTSTRAP_PASSWORD)",
+				"$(WORDPRESS_BOOTSTRAP_EMAIL)",
+			},
+		},
+	}
+}
+
+func (wp *Wordpress) migrationsContainer() []corev1.Container {
+	if wp.Spec.Migrations == nil || len(wp.Spec.Migrations.Command) == 0 {
+		return []corev1.Container{}
+	}
+
+	return []corev1.Container{
+		{
+			Name:                     "migrations",
+			Image:                    wp.image(),
+			ImagePullPolicy:          wp.Spec.ImagePullPolicy,
+			VolumeMounts:             wp.volumeMounts(),
+			Env:                      append(wp.env(), wp.Spec.Migrations.Env...),
+			EnvFrom:                  append(wp.envFrom(), wp.Spec.Migrations.EnvFrom...),
+			SecurityContext:          wp.securityContext(),
+			Command:                  wp.Spec.Migrations.Command,
+			TerminationMessagePolicy: wp.Spec.TerminationMessagePolicy,
+		},
+	}
+}
+
+func (wp *Wordpress) initContainers() []corev1.Container {
+	containers := []corev1.Container{}
+
+	containers = append(containers, wp.Spec.PreVolumeInitContainers...)
+
+	if wp.hasMediaMounts() || wp.hasCodeMounts() {
+		containers = append(containers, wp.prepareVolumesContainer())
+	}
+
+	if wp.Spec.VaultAgent != nil {
+		containers = append(containers, wp.vaultAgentContainer())
+	}
+
+	containers = append(containers, wp.Spec.InitContainers...)
+
+	if wp.Spec.CodeVolumeSpec != nil && wp.Spec.CodeVolumeSpec.GitDir != nil {
+		containers = append(containers, wp.gitCloneContainer())
+	}
+
+	if wp.hasConfigGitDir() {
+		containers = append(containers, wp.configGitCloneContainer())
+	}
+
+	// first clone data then install wp
+	containers = append(containers, wp.installWPContainer()...)
+
+	return containers
+}
+
+// imagePullSecrets returns the pod image pull secrets, which are left out
+// when they are set on the managed service account.
+func (wp *Wordpress) imagePullSecrets() []corev1.LocalObjectReference {
+	if wp.ManagesServiceAccount() {
+		return nil
+	}
+
+	return wp.Spec.ImagePullSecrets
+}
+
+// tolerations returns the site tolerations, adding the node not-ready and
+// unreachable ones when DefaultTolerationSeconds is set.
+func (wp *Wordpress) tolerations() []corev1.Toleration {
+	if wp.Spec.DefaultTolerationSeconds == nil {
+		if len(wp.Spec.Tolerations) == 0 {
+			return nil
+		}
+
+		return wp.Spec.Tolerations
+	}
+
+	out := append([]corev1.Toleration{}, wp.Spec.Tolerations...)
+
+	for _, key := range []string{corev1.TaintNodeNotReady, corev1.TaintNodeUnreachable} {
+		if hasToleration(out, key) {
+			continue
+		}
+
+		seconds := *wp.Spec.DefaultTolerationSeconds
+		out = append(out, corev1.Toleration{
+			Key:               key,
+			Operator:          corev1.TolerationOpExists,
+			Effect:            corev1.TaintEffectNoExecute,
+			TolerationSeconds: &seconds,
+		})
+	}
+
+	return out
+}
+
+// hasToleration reports whether the tolerations already tolerate the NoExecute
+// taint with the given key, including through a keyless Exists toleration.
+func hasToleration(tolerations []corev1.Toleration, key string) bool {
+	for _, t := range tolerations {
+		matches := t.Key == key || (t.Key == "" && t.Operator == corev1.TolerationOpExists)
+		if matches && (t.Effect == "" || t.Effect == corev1.TaintEffectNoExecute) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nodeSelector merges the site node selector over the operator default one.
+func (wp *Wordpress) nodeSelector() map[string]string {
+	if len(options.DefaultNodeSelector) == 0 && len(wp.Spec.NodeSelector) == 0 {
+		return nil
 	}
 
-	return c
+	return labels.Merge(options.DefaultNodeSelector, wp.Spec.NodeSelector)
 }
 
-func (wp *Wordpress) installWPContainer() []corev1.Container {
-	if wp.Spec.WordpressBootstrapSpec == nil {
-		return []corev1.Container{}
+// affinity returns .spec.affinity with the .spec.architecture node
+// requirement added to every required node selector term, since the terms
+// are ORed.
+func (wp *Wordpress) affinity() *corev1.Affinity {
+	if len(wp.Spec.Architecture) == 0 {
+		return wp.Spec.Affinity
 	}
 
-	return []corev1.Container{
-		{
-			Name:            "install-wp",
-			Image:           wp.Spec.Image,
-			VolumeMounts:    wp.volumeMounts(),
-			Env:             append(wp.env(), wp.Spec.WordpressBootstrapSpec.Env...),
-			EnvFrom:         append(wp.envFrom(), wp.Spec.WordpressBootstrapSpec.EnvFrom...),
-			SecurityContext: wp.securityContext(),
-			Command:         []string{"wp-install"},
-			Args: []string{
-				"$(WORDPRESS_BOOTSTRAP_TITLE)",
-				wp.HomeURL(),
-				"$(WORDPRESS_BOOTSTRAP_USER)",
-				"$(WORDPRESS_BOOTSTRAP_PASSWORD)",
-				"$(WORDPRESS_BOOTSTRAP_EMAIL)",
-			},
-		},
+	out := &corev1.Affinity{}
+	if wp.Spec.Affinity != nil {
+		out = wp.Spec.Affinity.DeepCopy()
 	}
-}
 
-func (wp *Wordpress) initContainers() []corev1.Container {
-	containers := []corev1.Container{}
+	if out.NodeAffinity == nil {
+		out.NodeAffinity = &corev1.NodeAffinity{}
+	}
 
-	if wp.hasMediaMounts() || wp.hasCodeMounts() {
-		containers = append(containers, wp.prepareVolumesContainer())
+	if out.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		out.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
 	}
 
-	containers = append(containers, wp.Spec.InitContainers...)
+	required := out.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(required.NodeSelectorTerms) == 0 {
+		required.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
 
-	if wp.Spec.CodeVolumeSpec != nil && wp.Spec.CodeVolumeSpec.GitDir != nil {
-		containers = append(containers, wp.gitCloneContainer())
+	for i := range required.NodeSelectorTerms {
+		required.NodeSelectorTerms[i].MatchExpressions = append(required.NodeSelectorTerms[i].MatchExpressions,
+			corev1.NodeSelectorRequirement{
+				Key:      corev1.LabelArchStable,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{wp.Spec.Architecture},
+			})
 	}
 
-	// first clone data then install wp
-	containers = append(containers, wp.installWPContainer()...)
+	return out
+}
 
-	return containers
+func (wp *Wordpress) resources() corev1.ResourceRequirements {
+	if len(wp.Spec.Resources.Requests) == 0 && len(wp.Spec.Resources.Limits) == 0 {
+		return *options.DefaultResources.DeepCopy()
+	}
+
+	return wp.Spec.Resources
 }
 
 func (wp *Wordpress) readinessProbe() *corev1.Probe {
@@ -537,45 +1817,299 @@ func (wp *Wordpress) readinessProbe() *corev1.Probe {
 		return wp.Spec.ReadinessProbe
 	}
 
-	return &corev1.Probe{
-		Handler: corev1.Handler{
-			HTTPGet: &corev1.HTTPGetAction{
-				Path: "/",
-				Port: intstr.FromInt(InternalHTTPPort),
-				HTTPHeaders: []corev1.HTTPHeader{
-					{
-						Name:  "Host",
-						Value: wp.MainDomain(),
-					},
-				},
-			},
+	handler := corev1.Handler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Path:   "/",
+			Port:   intstr.FromInt(InternalHTTPPort),
+			Scheme: wp.Spec.ProbeScheme,
+			HTTPHeaders: wp.probeHeaders(corev1.HTTPHeader{
+				Name:  "Host",
+				Value: wp.MainDomain(),
+			}),
 		},
-		FailureThreshold:    3,
+	}
+
+	if wp.Spec.MultiRouteReadiness || wp.hasMediaReadinessCheck() {
+		handler = wp.execReadinessHandler()
+	}
+
+	timeout, period, failureThreshold := wp.probeTiming()
+
+	return &corev1.Probe{
+		Handler:             handler,
+		FailureThreshold:    failureThreshold,
 		InitialDelaySeconds: 10,
-		PeriodSeconds:       5,
+		PeriodSeconds:       period,
 		SuccessThreshold:    1,
-		TimeoutSeconds:      30,
+		TimeoutSeconds:      timeout,
+	}
+}
+
+// probeTiming returns the timeout, period and failure threshold of the
+// default readiness and liveness probes.
+func (wp *Wordpress) probeTiming() (timeout, period, failureThreshold int32) {
+	timeout, period, failureThreshold = 30, 5, 3
+
+	if d := wp.Spec.ProbeDefaults; d != nil {
+		if d.TimeoutSeconds > 0 {
+			timeout = d.TimeoutSeconds
+		}
+
+		if d.PeriodSeconds > 0 {
+			period = d.PeriodSeconds
+		}
+
+		if d.FailureThreshold > 0 {
+			failureThreshold = d.FailureThreshold
+		}
+	}
+
+	return timeout, period, failureThreshold
+}
+
+// ValidateProbeDefaults checks that the default probes, as tuned by
+// .spec.probeDefaults, time out before failing by their period alone. The
+// untuned defaults are left unchecked, for compatibility.
+func (wp *Wordpress) ValidateProbeDefaults() error {
+	if wp.Spec.ProbeDefaults == nil || *wp.Spec.ProbeDefaults == (wordpressv1alpha1.ProbeDefaultsSpec{}) {
+		return nil
+	}
+
+	timeout, period, failureThreshold := wp.probeTiming()
+	if timeout >= period*failureThreshold {
+		return fmt.Errorf("%w: timeoutSeconds %d must be lower than periodSeconds %d * failureThreshold %d",
+			errProbeDefaults, timeout, period, failureThreshold)
 	}
+
+	return nil
+}
+
+// probeHeaders returns the given default probe headers, merged with the
+// .spec.probeHeaders by name.
+func (wp *Wordpress) probeHeaders(defaults ...corev1.HTTPHeader) []corev1.HTTPHeader {
+	var out []corev1.HTTPHeader
+
+	for _, h := range defaults {
+		overridden := false
+
+		for _, ph := range wp.Spec.ProbeHeaders {
+			if strings.EqualFold(h.Name, ph.Name) {
+				overridden = true
+			}
+		}
+
+		if !overridden {
+			out = append(out, h)
+		}
+	}
+
+	return append(out, wp.Spec.ProbeHeaders...)
+}
+
+// execReadinessHandler runs the media readiness check, when configured, then
+// requests the main domain, or each route in turn with MultiRouteReadiness,
+// and fails on the first one responding with an error. Like the HTTP probe,
+// redirects are not followed and count as success.
+func (wp *Wordpress) execReadinessHandler() corev1.Handler {
+	scheme, insecure := "http", ""
+	if wp.Spec.ProbeScheme == corev1.URISchemeHTTPS {
+		scheme, insecure = "https", " -k"
+	}
+
+	headers := ""
+
+	for _, h := range wp.Spec.ProbeHeaders {
+		if !strings.EqualFold(h.Name, "Host") {
+			headers += fmt.Sprintf(" -H '%s: %s'", h.Name, strings.ReplaceAll(h.Value, "'", `'\''`))
+		}
+	}
+
+	script := []string{"set -e"}
+
+	if wp.hasMediaReadinessCheck() {
+		script = append(script, shellQuote(wp.Spec.MediaReadinessCheck.Command))
+	}
+
+	routes := []string{wp.MainDomain()}
+	if wp.Spec.MultiRouteReadiness {
+		routes = wp.routes()
+	}
+
+	for _, route := range routes {
+		parts := strings.SplitN(route, "/", 2)
+
+		routePath := "/"
+		if len(parts) == 2 {
+			routePath += parts[1]
+		}
+
+		script = append(script, fmt.Sprintf(
+			"curl -sS -f%s -o /dev/null -H 'Host: %s'%s '%s://127.0.0.1:%d%s'",
+			insecure, parts[0], headers, scheme, InternalHTTPPort, routePath))
+	}
+
+	return corev1.Handler{
+		Exec: &corev1.ExecAction{
+			Command: []string{"/bin/sh", "-c", strings.Join(script, " ; ")},
+		},
+	}
+}
+
+// shellQuote single quotes the given arguments for use in a shell command.
+func shellQuote(args []string) string {
+	quoted := make([]string, len(args))
+
+	for i, arg := range args {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+
+	return strings.Join(quoted, " ")
 }
 
 func (wp *Wordpress) livenessProbe() *corev1.Probe {
+	if wp.Spec.DisableLivenessProbe {
+		return nil
+	}
+
 	if wp.Spec.LivenessProbe != nil {
 		return wp.Spec.LivenessProbe
 	}
 
+	timeout, period, failureThreshold := wp.probeTiming()
+
 	return &corev1.Probe{
 		Handler: corev1.Handler{
 			HTTPGet: &corev1.HTTPGetAction{
-				Path: "/-/php-ping",
-				Port: intstr.FromInt(InternalHTTPPort),
+				Path:        "/-/php-ping",
+				Port:        intstr.FromInt(InternalHTTPPort),
+				Scheme:      wp.Spec.ProbeScheme,
+				HTTPHeaders: wp.probeHeaders(),
 			},
 		},
-		FailureThreshold:    3,
+		FailureThreshold:    failureThreshold,
 		InitialDelaySeconds: 10,
-		PeriodSeconds:       5,
+		PeriodSeconds:       period,
 		SuccessThreshold:    1,
-		TimeoutSeconds:      30,
+		TimeoutSeconds:      timeout,
+	}
+}
+
+func (wp *Wordpress) startupProbe() *corev1.Probe {
+	if wp.Spec.StartupProbe != nil {
+		return wp.Spec.StartupProbe
+	}
+
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   "/-/php-ping",
+				Port:   intstr.FromInt(InternalHTTPPort),
+				Scheme: wp.Spec.ProbeScheme,
+			},
+		},
+		FailureThreshold: 30,
+		PeriodSeconds:    10,
+		SuccessThreshold: 1,
+		TimeoutSeconds:   5,
+	}
+}
+
+// startupBudgetSeconds returns how long the wordpress container may take to
+// start before the startup probe fails it.
+func (wp *Wordpress) startupBudgetSeconds() int32 {
+	p := wp.startupProbe()
+
+	return p.InitialDelaySeconds + p.FailureThreshold*p.PeriodSeconds
+}
+
+// ValidateWarmup checks that at most one of Warmup and PostStartWarmupPath is
+// set, since the native hook doesn't run the warmup requests.
+func (wp *Wordpress) ValidateWarmup() error {
+	if wp.Spec.Warmup != nil && wp.Spec.PostStartWarmupPath != "" {
+		return fmt.Errorf("%w: warmup and postStartWarmupPath can't be both set", errWarmup)
+	}
+
+	return nil
+}
+
+// postStartHandler runs the image post-start scripts, then requests the
+// warmup paths, if any. Failing warmup requests don't fail the container.
+// With PostStartWarmupPath, it only requests that path, natively.
+func (wp *Wordpress) postStartHandler() *corev1.Handler {
+	if wp.Spec.PostStartWarmupPath != "" {
+		return &corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   wp.Spec.PostStartWarmupPath,
+				Port:   intstr.FromInt(InternalHTTPPort),
+				Scheme: wp.Spec.ProbeScheme,
+				HTTPHeaders: wp.probeHeaders(corev1.HTTPHeader{
+					Name:  "Host",
+					Value: wp.MainDomain(),
+				}),
+			},
+		}
+	}
+
+	script := []string{postStartScriptsCmd}
+
+	if wp.Spec.Warmup != nil {
+		script = append([]string{"set -e"}, script...)
+
+		scheme, insecure := "http", ""
+		if wp.Spec.ProbeScheme == corev1.URISchemeHTTPS {
+			scheme, insecure = "https", " -k"
+		}
+
+		for _, p := range wp.Spec.Warmup.Paths {
+			script = append(script, fmt.Sprintf(
+				"curl -sS%s -o /dev/null --max-time 30 --retry 5 --retry-connrefused -H 'Host: %s' '%s://127.0.0.1:%d%s' || true",
+				insecure, wp.MainDomain(), scheme, InternalHTTPPort, p))
+		}
+	}
+
+	return &corev1.Handler{
+		Exec: &corev1.ExecAction{
+			Command: []string{"/bin/sh", "-c", strings.Join(script, " ; ")},
+		},
+	}
+}
+
+// preStopHandler drains the web server, when configured, then runs the image pre-stop scripts.
+func (wp *Wordpress) preStopHandler() *corev1.Handler {
+	script := []string{}
+
+	if wp.Spec.DrainEndpoint != "" {
+		script = append(script, fmt.Sprintf("curl -fsS --max-time 5 'http://127.0.0.1:%d%s' || true", InternalHTTPPort, wp.Spec.DrainEndpoint))
+	}
+
+	if wp.Spec.DrainSeconds > 0 {
+		script = append(script, fmt.Sprintf("sleep %d", wp.Spec.DrainSeconds))
+	}
+
+	if delay := wp.wordpressShutdownDelay(); delay > 0 {
+		script = append(script, fmt.Sprintf("sleep %d", delay))
 	}
+
+	if wp.Spec.PreStopBestEffort {
+		script = append(script, preStopScriptsBestEffortCmd)
+	} else {
+		script = append(script, preStopScriptsCmd)
+	}
+
+	return &corev1.Handler{
+		Exec: &corev1.ExecAction{
+			Command: []string{"/bin/sh", "-c", strings.Join(script, " ; ")},
+		},
+	}
+}
+
+// terminationGracePeriodSeconds extends the default grace period with the
+// drain and shutdown delay periods.
+func (wp *Wordpress) terminationGracePeriodSeconds() *int64 {
+	period := corev1.DefaultTerminationGracePeriodSeconds + int64(wp.Spec.DrainSeconds) +
+		int64(wp.wordpressShutdownDelay())
+
+	return &period
 }
 
 // WebPodTemplateSpec generates a pod template spec suitable for use in Wordpress deployment.
@@ -587,125 +2121,219 @@ func (wp *Wordpress) WebPodTemplateSpec() (out corev1.PodTemplateSpec) {
 		wp.Spec.PodMetadata.DeepCopyInto(&out.ObjectMeta)
 	}
 
-	out.ObjectMeta.Labels = labels.Merge(out.ObjectMeta.Labels, wp.WebPodLabels())
+	out.ObjectMeta.Labels = labels.Merge(labels.Merge(options.CommonLabels, out.ObjectMeta.Labels), wp.WebPodLabels())
+	wp.setImageTagAnnotation(&out)
+	wp.setGitCommitAnnotation(&out)
 
-	out.Spec.ImagePullSecrets = wp.Spec.ImagePullSecrets
+	out.Spec.ImagePullSecrets = wp.imagePullSecrets()
 	if len(wp.Spec.ServiceAccountName) > 0 {
 		out.Spec.ServiceAccountName = wp.Spec.ServiceAccountName
 	}
 
-	out.Spec.InitContainers = wp.initContainers()
+	// migrations run on every rollout, after the code is cloned and wordpress is installed
+	out.Spec.InitContainers = append(wp.initContainers(), wp.migrationsContainer()...)
+
+	// only the web pods serve media files, so the job pods get an empty cache
+	if wp.hasMediaPreSync() {
+		out.Spec.InitContainers = append(out.Spec.InitContainers, wp.mediaPreSyncContainer())
+	}
+
 	wordpressContainer := corev1.Container{
-		Name:            "wordpress",
-		Image:           wp.Spec.Image,
+		Name:            wp.Spec.ContainerName,
+		Image:           wp.image(),
 		ImagePullPolicy: wp.Spec.ImagePullPolicy,
 		VolumeMounts:    wp.volumeMounts(),
 		Env:             wp.env(),
 		EnvFrom:         wp.envFrom(),
-		Resources:       wp.Spec.Resources,
+		Resources:       wp.resources(),
 		Ports: []corev1.ContainerPort{
 			{
 				Name:          "http",
 				ContainerPort: int32(InternalHTTPPort),
 			},
-			{
-				Name:          "prometheus",
-				ContainerPort: MetricsExporterPort,
-			},
 		},
-		SecurityContext: wp.securityContext(),
+		SecurityContext:          wp.securityContext(),
+		TerminationMessagePolicy: wp.Spec.TerminationMessagePolicy,
 		Lifecycle: &corev1.Lifecycle{
-			PostStart: &corev1.Handler{
-				Exec: &corev1.ExecAction{
-					Command: []string{
-						"/bin/sh", "-c",
-						"if test -n \"$POST_START_SCRIPTS\" && command -v run-parts >/dev/null 2>&1 && test -d \"$POST_START_SCRIPTS\"  ; then run-parts --exit-on-error -v \"$POST_START_SCRIPTS\" ; fi", // nolint: lll
-					},
-				},
-			},
-			PreStop: &corev1.Handler{
-				Exec: &corev1.ExecAction{
-					Command: []string{
-						"/bin/sh", "-c",
-						"if test -n \"$PRE_STOP_SCRIPTS\" && command -v run-parts >/dev/null 2>&1 && test -d \"$PRE_STOP_SCRIPTS\"  ; then run-parts --exit-on-error -v \"$PRE_STOP_SCRIPTS\" ; fi", // nolint: lll
-					},
-				},
-			},
+			PostStart: wp.postStartHandler(),
+			PreStop:   wp.preStopHandler(),
 		},
 		ReadinessProbe: wp.readinessProbe(),
 		LivenessProbe:  wp.livenessProbe(),
+		StartupProbe:   wp.startupProbe(),
 	}
-	out.Spec.Containers = append([]corev1.Container{wordpressContainer}, wp.Spec.Sidecars...)
-
-	out.Spec.Volumes = wp.volumes()
 
-	if len(wp.Spec.NodeSelector) > 0 {
-		out.Spec.NodeSelector = wp.Spec.NodeSelector
+	// the exporter sidecar serves the metrics in place of the wordpress container
+	if wp.Spec.FPMExporter == nil {
+		wordpressContainer.Ports = append(wordpressContainer.Ports, corev1.ContainerPort{
+			Name:          "prometheus",
+			ContainerPort: MetricsExporterPort,
+		})
 	}
 
-	if len(wp.Spec.Tolerations) > 0 {
-		out.Spec.Tolerations = wp.Spec.Tolerations
+	out.Spec.Containers = append([]corev1.Container{wordpressContainer}, wp.sidecars()...)
+
+	if wp.Spec.FPMExporter != nil {
+		out.Spec.Containers = append(out.Spec.Containers, wp.fpmExporterContainer())
 	}
 
-	out.Spec.Affinity = wp.Spec.Affinity
+	out.Spec.Volumes = wp.volumes()
+
+	out.Spec.NodeSelector = wp.nodeSelector()
+
+	out.Spec.Tolerations = wp.tolerations()
+
+	out.Spec.Affinity = wp.affinity()
 
 	if len(wp.Spec.PriorityClassName) > 0 {
 		out.Spec.PriorityClassName = wp.Spec.PriorityClassName
 	}
 
+	out.Spec.Overhead = wp.Spec.PodOverhead
+
+	out.Spec.EnableServiceLinks = wp.Spec.EnableServiceLinks
+	out.Spec.ShareProcessNamespace = wp.Spec.ShareProcessNamespace
+	out.Spec.TerminationGracePeriodSeconds = wp.terminationGracePeriodSeconds()
+
+	// the mounted SSH key is owned by root, so it must be group readable by the containers group
+	if wp.hasGitSSHKeySecret() || wp.usesFSGroupPermissions() {
+		out.Spec.SecurityContext = &corev1.PodSecurityContext{
+			FSGroup: wp.runAsGroup(),
+		}
+	}
+
+	if wp.usesFSGroupPermissions() {
+		onRootMismatch := corev1.FSGroupChangeOnRootMismatch
+		out.Spec.SecurityContext.FSGroupChangePolicy = &onRootMismatch
+	}
+
+	wp.setInitResources(&out.Spec)
+	mirrorImages(&out.Spec)
+
+	return out
+}
+
+// CanaryPodTemplateSpec generates a pod template spec suitable for use in the
+// canary Wordpress deployment. It's the web pod template running the canary image.
+func (wp *Wordpress) CanaryPodTemplateSpec() (out corev1.PodTemplateSpec) {
+	canary := New(wp.Wordpress.DeepCopy())
+	if wp.Spec.Canary != nil {
+		canary.Spec.Image = wp.Spec.Canary.Image
+	}
+
+	out = canary.WebPodTemplateSpec()
+	out.ObjectMeta.Labels = labels.Merge(out.ObjectMeta.Labels, wp.CanaryPodLabels())
+
+	return out
+}
+
+// StatefulSetPodTemplateSpec generates the web pod template for the
+// StatefulSet, leaving out the volumes provided by its claim templates.
+func (wp *Wordpress) StatefulSetPodTemplateSpec() (out corev1.PodTemplateSpec) {
+	out = wp.WebPodTemplateSpec()
+
+	if !wp.hasMediaClaimTemplate() {
+		return out
+	}
+
+	volumes := []corev1.Volume{}
+
+	for _, v := range out.Spec.Volumes {
+		if v.Name != mediaVolumeName {
+			volumes = append(volumes, v)
+		}
+	}
+
+	out.Spec.Volumes = volumes
+
 	return out
 }
 
 // JobPodTemplateSpec generates a pod template spec suitable for use in wp-cli jobs.
 func (wp *Wordpress) JobPodTemplateSpec(cmd ...string) (out corev1.PodTemplateSpec) {
+	// generate the job pods from a read-write media copy of the site, so the
+	// media mounts and the prepare-volumes chown match
+	if wp.Spec.JobMediaReadWrite && wp.Spec.MediaVolumeSpec != nil && wp.Spec.MediaVolumeSpec.ReadOnly {
+		rw := New(wp.Unwrap().DeepCopy())
+		rw.Spec.MediaVolumeSpec.ReadOnly = false
+
+		return rw.JobPodTemplateSpec(cmd...)
+	}
+
 	out = corev1.PodTemplateSpec{}
 
 	if wp.Spec.PodMetadata != nil {
 		wp.Spec.PodMetadata.DeepCopyInto(&out.ObjectMeta)
 	}
 
-	out.ObjectMeta.Labels = labels.Merge(out.ObjectMeta.Labels, wp.JobPodLabels())
+	out.ObjectMeta.Labels = labels.Merge(labels.Merge(options.CommonLabels, out.ObjectMeta.Labels), wp.JobPodLabels())
+	wp.setImageTagAnnotation(&out)
+	wp.setGitCommitAnnotation(&out)
 
-	out.Spec.ImagePullSecrets = wp.Spec.ImagePullSecrets
+	out.Spec.ImagePullSecrets = wp.imagePullSecrets()
 	if len(wp.Spec.ServiceAccountName) > 0 {
 		out.Spec.ServiceAccountName = wp.Spec.ServiceAccountName
 	}
 
+	// the job service account is not managed, so it doesn't hold the pull secrets
+	if len(wp.Spec.JobServiceAccountName) > 0 {
+		out.Spec.ServiceAccountName = wp.Spec.JobServiceAccountName
+		out.Spec.ImagePullSecrets = wp.Spec.ImagePullSecrets
+	}
+
 	out.Spec.RestartPolicy = corev1.RestartPolicyNever
 
 	out.Spec.InitContainers = wp.initContainers()
 	wordpressContainer := corev1.Container{
-		Name:            "wp-cli",
-		Image:           wp.Spec.Image,
-		ImagePullPolicy: wp.Spec.ImagePullPolicy,
-		Args:            cmd,
-		VolumeMounts:    wp.volumeMounts(),
-		Env:             wp.env(),
-		EnvFrom:         wp.envFrom(),
-		SecurityContext: wp.securityContext(),
+		Name:                     wp.Spec.CLIContainerName,
+		Image:                    wp.image(),
+		ImagePullPolicy:          wp.Spec.ImagePullPolicy,
+		Args:                     cmd,
+		VolumeMounts:             wp.volumeMounts(),
+		Env:                      wp.env(),
+		EnvFrom:                  wp.envFrom(),
+		SecurityContext:          wp.securityContext(),
+		TerminationMessagePolicy: wp.Spec.TerminationMessagePolicy,
+	}
+
+	if wp.Spec.JobProbe != nil {
+		wordpressContainer.ReadinessProbe = wp.Spec.JobProbe.DeepCopy()
 	}
-	out.Spec.Containers = append([]corev1.Container{wordpressContainer}, wp.Spec.Sidecars...)
+
+	out.Spec.Containers = append([]corev1.Container{wordpressContainer}, wp.sidecars()...)
 
 	out.Spec.Volumes = wp.volumes()
 
-	if len(wp.Spec.NodeSelector) > 0 {
-		out.Spec.NodeSelector = wp.Spec.NodeSelector
+	if wp.Spec.CaptureJobOutput && len(cmd) > 0 {
+		wp.captureJobOutput(&out.Spec)
 	}
 
-	if len(wp.Spec.Tolerations) > 0 {
-		out.Spec.Tolerations = wp.Spec.Tolerations
-	}
+	out.Spec.NodeSelector = wp.nodeSelector()
+
+	out.Spec.Tolerations = wp.tolerations()
 
-	out.Spec.Affinity = wp.Spec.Affinity
+	out.Spec.Affinity = wp.affinity()
 
 	if len(wp.Spec.PriorityClassName) > 0 {
 		out.Spec.PriorityClassName = wp.Spec.PriorityClassName
 	}
 
+	out.Spec.Overhead = wp.Spec.PodOverhead
+
+	out.Spec.EnableServiceLinks = wp.Spec.EnableServiceLinks
+
 	out.Spec.SecurityContext = &corev1.PodSecurityContext{
-		FSGroup: &wwwDataUserID,
+		FSGroup: wp.runAsGroup(),
 	}
 
+	if wp.usesFSGroupPermissions() {
+		onRootMismatch := corev1.FSGroupChangeOnRootMismatch
+		out.Spec.SecurityContext.FSGroupChangePolicy = &onRootMismatch
+	}
+
+	wp.setInitResources(&out.Spec)
+	mirrorImages(&out.Spec)
+
 	return out
 }
 
@@ -717,6 +2345,10 @@ func (wp *Wordpress) hasMediaMounts() bool {
 	switch {
 	case wp.Spec.MediaVolumeSpec.PersistentVolumeClaim != nil:
 		return true
+	case wp.Spec.MediaVolumeSpec.CephFS != nil:
+		return true
+	case wp.Spec.MediaVolumeSpec.Glusterfs != nil:
+		return true
 	case wp.Spec.MediaVolumeSpec.HostPath != nil:
 		return true
 	case wp.Spec.MediaVolumeSpec.EmptyDir != nil:
@@ -726,6 +2358,21 @@ func (wp *Wordpress) hasMediaMounts() bool {
 	return false
 }
 
+// isMediaReadOnly returns true if the media volume is mounted read-only or
+// its network volume source is read-only.
+func (wp *Wordpress) isMediaReadOnly() bool {
+	switch {
+	case wp.Spec.MediaVolumeSpec.ReadOnly:
+		return true
+	case wp.Spec.MediaVolumeSpec.CephFS != nil:
+		return wp.Spec.MediaVolumeSpec.CephFS.ReadOnly
+	case wp.Spec.MediaVolumeSpec.Glusterfs != nil:
+		return wp.Spec.MediaVolumeSpec.Glusterfs.ReadOnly
+	}
+
+	return false
+}
+
 func (wp *Wordpress) hasCodeMounts() bool {
 	if wp.Spec.CodeVolumeSpec == nil {
 		return false
@@ -744,3 +2391,73 @@ func (wp *Wordpress) hasCodeMounts() bool {
 
 	return false
 }
+
+func (wp *Wordpress) hasGitSSHKeySecret() bool {
+	return wp.Spec.CodeVolumeSpec != nil && wp.Spec.CodeVolumeSpec.GitDir != nil &&
+		wp.Spec.CodeVolumeSpec.GitDir.SSHKeySecret != nil
+}
+
+func (wp *Wordpress) hasMediaToken() bool {
+	return wp.Spec.MediaVolumeSpec != nil && wp.Spec.MediaVolumeSpec.TokenAudience != "" &&
+		wp.Spec.MediaVolumeSpec.RoleARN != ""
+}
+
+func (wp *Wordpress) hasConfigGitDir() bool {
+	return wp.Spec.CodeVolumeSpec != nil && wp.Spec.CodeVolumeSpec.ConfigGitDir != nil
+}
+
+func (wp *Wordpress) hasGitCABundleSecret() bool {
+	return wp.Spec.CodeVolumeSpec != nil && wp.Spec.CodeVolumeSpec.GitDir != nil &&
+		wp.Spec.CodeVolumeSpec.GitDir.CABundleSecret != nil
+}
+
+func (wp *Wordpress) hasMediaReadinessCheck() bool {
+	return wp.Spec.MediaReadinessCheck != nil && wp.Spec.MediaVolumeSpec != nil &&
+		(wp.Spec.MediaVolumeSpec.S3VolumeSource != nil || wp.Spec.MediaVolumeSpec.GCSVolumeSource != nil)
+}
+
+func (wp *Wordpress) hasGitVerifySignature() bool {
+	return wp.Spec.CodeVolumeSpec != nil && wp.Spec.CodeVolumeSpec.GitDir != nil &&
+		wp.Spec.CodeVolumeSpec.GitDir.VerifySignature != nil
+}
+
+func (wp *Wordpress) hasGitReferenceRepo() bool {
+	return wp.Spec.CodeVolumeSpec != nil && wp.Spec.CodeVolumeSpec.GitDir != nil &&
+		wp.Spec.CodeVolumeSpec.GitDir.ReferenceRepoPath != ""
+}
+
+// DebugPodTemplateSpec generates a wp-cli job pod template spec which idles,
+// for exec-ing into the site environment when debugging. It is never used by
+// the controller.
+func (wp *Wordpress) DebugPodTemplateSpec() corev1.PodTemplateSpec {
+	debug := New(wp.Unwrap().DeepCopy())
+	debug.Spec.CaptureJobOutput = false
+	debug.Spec.JobProbe = nil
+
+	return debug.JobPodTemplateSpec(idleCmd...)
+}
+
+// captureJobOutput wraps the wp-cli job command to tee its output to the job
+// output volume, which is mounted in all the job pod containers.
+func (wp *Wordpress) captureJobOutput(spec *corev1.PodSpec) {
+	mount := corev1.VolumeMount{
+		Name:      jobOutputVolume,
+		MountPath: jobOutputMountPath,
+	}
+
+	// copy the mounts, as the sidecars may share them with the wordpress spec
+	for i := range spec.Containers {
+		mounts := append([]corev1.VolumeMount{}, spec.Containers[i].VolumeMounts...)
+		spec.Containers[i].VolumeMounts = append(mounts, mount)
+	}
+
+	cli := &spec.Containers[0]
+	cli.Args = append([]string{"/bin/bash", "-c", jobOutputCaptureCmd, "wp-cli"}, cli.Args...)
+
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: jobOutputVolume,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	})
+}
@@ -19,13 +19,20 @@ package wordpress
 import (
 	"fmt"
 	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	wordpressv1alpha1 "github.com/bitpoke/wordpress-operator/pkg/apis/wordpress/v1alpha1"
@@ -92,6 +99,31 @@ var _ = Describe("Web pod spec", func() {
 		}),
 	)
 
+	DescribeTable("Should run the pre-volume init containers before prepare-volumes",
+		func(f func() (func() corev1.PodTemplateSpec, *Wordpress)) {
+			podSpec, w := f()
+
+			w.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+				GitDir: &wordpressv1alpha1.GitVolumeSource{},
+			}
+			w.Spec.InitContainers = []corev1.Container{{Name: "init"}}
+			w.Spec.PreVolumeInitContainers = []corev1.Container{{Name: "mkdir"}}
+			containers := podSpec().Spec.InitContainers
+
+			Expect(containers).To(HaveLen(4))
+			Expect(containers[0].Name).To(Equal("mkdir"))
+			Expect(containers[1].Name).To(Equal("prepare-volumes"))
+			Expect(containers[2].Name).To(Equal("init"))
+			Expect(containers[3].Name).To(Equal("git"))
+		},
+		Entry("for web pod", func() (func() corev1.PodTemplateSpec, *Wordpress) {
+			return wp.WebPodTemplateSpec, wp
+		}),
+		Entry("for job pod", func() (func() corev1.PodTemplateSpec, *Wordpress) {
+			return func() corev1.PodTemplateSpec { return wp.JobPodTemplateSpec("test") }, wp
+		}),
+	)
+
 	DescribeTable("Should generate an init contaniner, used to install Wordpress",
 		func(f func() (func() corev1.PodTemplateSpec, *Wordpress)) {
 			// we need this hack to allow wp to be initialized with our custom values
@@ -163,6 +195,121 @@ var _ = Describe("Web pod spec", func() {
 		Expect(e.Value).To(Equal("test.com,test.org/abc,test.net/xyz"))
 	})
 
+	It("should expose the computed container env", func() {
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			S3VolumeSource: &wordpressv1alpha1.S3VolumeSource{
+				Bucket:     "test-bucket",
+				PathPrefix: "test-prefix",
+			},
+		}
+
+		spec := wp.WebPodTemplateSpec()
+		Expect(wp.ContainerEnv()).To(Equal(spec.Spec.Containers[0].Env))
+		Expect(wp.ContainerEnvFrom()).To(Equal(spec.Spec.Containers[0].EnvFrom))
+
+		e, found := lookupEnvVar("STACK_MEDIA_BUCKET", wp.ContainerEnv())
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("s3://test-bucket/test-prefix"))
+	})
+
+	DescribeTable("should expand env references in the media path prefix",
+		func(media *wordpressv1alpha1.MediaVolumeSpec, bucket string) {
+			wp.Spec.Env = []corev1.EnvVar{{Name: "STACK_ENV", Value: "staging"}}
+			wp.Spec.MediaVolumeSpec = media
+
+			for _, spec := range []corev1.PodSpec{wp.WebPodTemplateSpec().Spec, wp.JobPodTemplateSpec().Spec} {
+				env := spec.Containers[0].Env
+				index := map[string]int{}
+				for i, e := range env {
+					index[e.Name] = i
+				}
+
+				e, _ := lookupEnvVar("STACK_MEDIA_BUCKET", env)
+				Expect(e.Value).To(Equal(bucket))
+				// $(VAR_NAME) references only expand to the vars defined before
+				Expect(index).To(HaveKey("STACK_SITE_NAMESPACE"))
+				Expect(index).To(HaveKey("STACK_ENV"))
+				Expect(index["STACK_SITE_NAMESPACE"]).To(BeNumerically("<", index["STACK_MEDIA_BUCKET"]))
+				Expect(index["STACK_ENV"]).To(BeNumerically("<", index["STACK_MEDIA_BUCKET"]))
+			}
+		},
+		Entry("for s3", &wordpressv1alpha1.MediaVolumeSpec{
+			S3VolumeSource: &wordpressv1alpha1.S3VolumeSource{
+				Bucket:     "test-bucket",
+				PathPrefix: "$(STACK_SITE_NAMESPACE)/$(STACK_ENV)",
+			},
+		}, "s3://test-bucket/$(STACK_SITE_NAMESPACE)/$(STACK_ENV)"),
+		Entry("for gcs", &wordpressv1alpha1.MediaVolumeSpec{
+			GCSVolumeSource: &wordpressv1alpha1.GCSVolumeSource{
+				Bucket:     "test-bucket",
+				PathPrefix: "$(STACK_SITE_NAMESPACE)/$(STACK_ENV)",
+			},
+		}, "gs://test-bucket/$(STACK_SITE_NAMESPACE)/$(STACK_ENV)"),
+	)
+
+	It("should detect selectors no longer matching the web pods", func() {
+		podLabels := wp.WebPodTemplateSpec().Labels
+
+		Expect(SelectorChanged(metav1.SetAsLabelSelector(wp.WebPodSelectorLabels()), podLabels)).To(BeFalse())
+		Expect(SelectorChanged(metav1.SetAsLabelSelector(wp.WebPodLabels()), podLabels)).To(BeFalse())
+		Expect(SelectorChanged(&metav1.LabelSelector{}, podLabels)).To(BeTrue())
+		Expect(SelectorChanged(nil, podLabels)).To(BeTrue())
+
+		legacy := metav1.SetAsLabelSelector(wp.WebPodLabels())
+		wp.ObjectMeta.Labels = map[string]string{"app.kubernetes.io/part-of": "blog"}
+		Expect(SelectorChanged(legacy, wp.WebPodTemplateSpec().Labels)).To(BeTrue())
+	})
+
+	It("should roll out on the envFrom secrets only when opted in", func() {
+		wp.Spec.EnvFrom = []corev1.EnvFromSource{
+			{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}}},
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "config"}}},
+		}
+		Expect(wp.RolloutSecretNames()).To(BeEmpty())
+
+		wp.Spec.RestartOnSecretChange = true
+		Expect(wp.RolloutSecretNames()).To(Equal([]string{"db"}))
+	})
+
+	It("should select the web and canary pods apart", func() {
+		wp.Spec.Canary = &wordpressv1alpha1.CanarySpec{Image: "canary:latest"}
+		web, err := metav1.LabelSelectorAsSelector(wp.WebPodSelector())
+		Expect(err).ToNot(HaveOccurred())
+		canary := labels.SelectorFromSet(wp.CanaryPodSelectorLabels())
+
+		Expect(web.Matches(labels.Set(wp.WebPodTemplateSpec().Labels))).To(BeTrue())
+		Expect(web.Matches(labels.Set(wp.CanaryPodTemplateSpec().Labels))).To(BeFalse())
+		Expect(canary.Matches(labels.Set(wp.CanaryPodTemplateSpec().Labels))).To(BeTrue())
+		Expect(canary.Matches(labels.Set(wp.WebPodTemplateSpec().Labels))).To(BeFalse())
+
+		// the service sends traffic to both
+		service := labels.SelectorFromSet(wp.WebPodSelectorLabels())
+		Expect(service.Matches(labels.Set(wp.CanaryPodTemplateSpec().Labels))).To(BeTrue())
+	})
+
+	It("should enable the debug mode when configured", func() {
+		_, found := lookupEnvVar("WP_DEBUG", wp.ContainerEnv())
+		Expect(found).To(BeFalse())
+
+		wp.Spec.Debug = &wordpressv1alpha1.DebugSpec{}
+		wp.SetDefaults()
+		_, found = lookupEnvVar("WP_DEBUG", wp.ContainerEnv())
+		Expect(found).To(BeFalse())
+
+		wp.Spec.Debug.Enabled = true
+		env := wp.WebPodTemplateSpec().Spec.Containers[0].Env
+		e, _ := lookupEnvVar("WP_DEBUG", env)
+		Expect(e.Value).To(Equal("true"))
+		e, _ = lookupEnvVar("WP_DEBUG_LOG", env)
+		Expect(e.Value).To(Equal("/var/log/wp-debug.log"))
+		e, _ = lookupEnvVar("WP_DEBUG_DISPLAY", env)
+		Expect(e.Value).To(Equal("false"))
+
+		wp.Spec.Debug.LogPath = "../../etc/debug.log"
+		e, _ = lookupEnvVar("WP_DEBUG_LOG", wp.ContainerEnv())
+		Expect(e.Value).To(Equal("/var/log/etc/debug.log"))
+	})
+
 	It("should give me the default domain", func() {
 		Expect(wp.MainDomain()).To(Equal("test.com"))
 
@@ -186,6 +333,49 @@ var _ = Describe("Web pod spec", func() {
 		Expect(wp.HomeURL()).To(Equal("http://test.com/subpath"))
 	})
 
+	It("should tell whether the site is in its maintenance window", func() {
+		at := func(hhmm string) time.Time {
+			t, err := time.Parse("2006-01-02 15:04", "2021-10-10 "+hhmm)
+			Expect(err).ToNot(HaveOccurred())
+			return t
+		}
+
+		in, next := wp.InMaintenanceWindow(at("12:00"))
+		Expect(in).To(BeFalse())
+		Expect(next).To(BeZero())
+
+		wp.Spec.MaintenanceWindow = &wordpressv1alpha1.MaintenanceWindowSpec{Start: "09:00", End: "17:30"}
+		in, next = wp.InMaintenanceWindow(at("09:00"))
+		Expect(in).To(BeTrue())
+		Expect(next).To(Equal(8*time.Hour + 30*time.Minute))
+
+		in, next = wp.InMaintenanceWindow(at("17:30"))
+		Expect(in).To(BeFalse())
+		Expect(next).To(Equal(15*time.Hour + 30*time.Minute))
+
+		wp.Spec.MaintenanceWindow = &wordpressv1alpha1.MaintenanceWindowSpec{Start: "22:00", End: "02:00"}
+		in, next = wp.InMaintenanceWindow(at("01:00"))
+		Expect(in).To(BeTrue())
+		Expect(next).To(Equal(time.Hour))
+
+		in, next = wp.InMaintenanceWindow(at("21:00"))
+		Expect(in).To(BeFalse())
+		Expect(next).To(Equal(time.Hour))
+	})
+
+	It("should derive the home URL from the primary route", func() {
+		wp.Spec.Routes = append(wp.Spec.Routes, wordpressv1alpha1.RouteSpec{Domain: "www.test.com", Path: "/blog"})
+		Expect(wp.MainDomain()).To(Equal("test.com"))
+
+		wp.Spec.Routes[1].Primary = true
+		Expect(wp.MainDomain()).To(Equal("www.test.com"))
+		Expect(wp.SiteURL()).To(Equal("http://www.test.com/blog/wp"))
+		Expect(wp.ValidateRoutes()).To(Succeed())
+
+		wp.Spec.Routes[0].Primary = true
+		Expect(wp.ValidateRoutes()).ToNot(Succeed())
+	})
+
 	It("should give me the default readiness probe", func() {
 		spec := wp.WebPodTemplateSpec()
 
@@ -210,6 +400,309 @@ var _ = Describe("Web pod spec", func() {
 		}))
 	})
 
+	It("should expose the container resource limits when configured", func() {
+		_, found := lookupEnvVar("CONTAINER_MEMORY_LIMIT", wp.WebPodTemplateSpec().Spec.Containers[0].Env)
+		Expect(found).To(BeFalse())
+
+		wp.Spec.ExposeResourceLimits = true
+		env := wp.WebPodTemplateSpec().Spec.Containers[0].Env
+
+		e, found := lookupEnvVar("CONTAINER_MEMORY_LIMIT", env)
+		Expect(found).To(BeTrue())
+		Expect(e.ValueFrom.ResourceFieldRef.Resource).To(Equal("limits.memory"))
+
+		e, found = lookupEnvVar("CONTAINER_CPU_LIMIT", env)
+		Expect(found).To(BeTrue())
+		Expect(e.ValueFrom.ResourceFieldRef.Resource).To(Equal("limits.cpu"))
+	})
+
+	It("should expose the memory limit to the runtime when configured", func() {
+		wp.Spec.ExposeMemoryLimitEnv = true
+		_, found := lookupEnvVar("GOMEMLIMIT", wp.WebPodTemplateSpec().Spec.Containers[0].Env)
+		Expect(found).To(BeFalse())
+
+		wp.Spec.Resources.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")}
+		env := wp.WebPodTemplateSpec().Spec.Containers[0].Env
+
+		for _, name := range []string{"GOMEMLIMIT", "RUNTIME_MEMORY_LIMIT"} {
+			e, found := lookupEnvVar(name, env)
+			Expect(found).To(BeTrue())
+			Expect(e.ValueFrom.ResourceFieldRef.Resource).To(Equal("limits.memory"))
+		}
+	})
+
+	It("should run the php-fpm exporter sidecar when configured", func() {
+		Expect(wp.WebPodTemplateSpec().Spec.Containers[0].Ports).To(ContainElement(corev1.ContainerPort{
+			Name:          "prometheus",
+			ContainerPort: MetricsExporterPort,
+		}))
+
+		wp.Spec.FPMExporter = &wordpressv1alpha1.FPMExporterSpec{}
+		wp.SetDefaults()
+		spec := wp.WebPodTemplateSpec().Spec
+
+		Expect(spec.Containers[0].Ports).To(HaveLen(1))
+		Expect(spec.Containers[0].VolumeMounts).To(ContainElement(wp.sharedSocketVolumeMount()))
+
+		exporter := spec.Containers[len(spec.Containers)-1]
+		Expect(exporter.Name).To(Equal("fpm-exporter"))
+		Expect(exporter.Image).To(Equal(defaultFPMExporterImage))
+		Expect(exporter.Ports).To(Equal([]corev1.ContainerPort{{Name: "prometheus", ContainerPort: MetricsExporterPort}}))
+		Expect(exporter.VolumeMounts).To(Equal([]corev1.VolumeMount{wp.sharedSocketVolumeMount()}))
+
+		e, found := lookupEnvVar("PHP_FPM_SCRAPE_URI", exporter.Env)
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("unix:///var/run/sockets/php-fpm.sock;/status"))
+
+		Expect(exporter.LivenessProbe.TCPSocket.Port).To(Equal(intstr.FromString("prometheus")))
+		Expect(exporter.ReadinessProbe.TCPSocket.Port).To(Equal(intstr.FromString("prometheus")))
+	})
+
+	It("should allow overriding the php-fpm exporter probes", func() {
+		probe := &corev1.Probe{
+			Handler: corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path: "/metrics",
+					Port: intstr.FromString("prometheus"),
+				},
+			},
+		}
+		wp.Spec.FPMExporter = &wordpressv1alpha1.FPMExporterSpec{
+			LivenessProbe:  probe,
+			ReadinessProbe: probe,
+		}
+		wp.SetDefaults()
+		spec := wp.WebPodTemplateSpec().Spec
+
+		exporter := spec.Containers[len(spec.Containers)-1]
+		Expect(exporter.LivenessProbe).To(Equal(probe))
+		Expect(exporter.ReadinessProbe).To(Equal(probe))
+	})
+
+	It("should give the wordpress container a long startup budget", func() {
+		Expect(*wp.WebPodTemplateSpec().Spec.Containers[0].StartupProbe).To(Equal(corev1.Probe{
+			Handler: corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path: "/-/php-ping",
+					Port: intstr.FromInt(InternalHTTPPort),
+				},
+			},
+			FailureThreshold: 30,
+			PeriodSeconds:    10,
+			SuccessThreshold: 1,
+			TimeoutSeconds:   5,
+		}))
+		Expect(wp.startupBudgetSeconds()).To(Equal(int32(300)))
+
+		liveness := wp.livenessProbe()
+		Expect(wp.startupBudgetSeconds()).To(BeNumerically(">", liveness.InitialDelaySeconds+liveness.FailureThreshold*liveness.PeriodSeconds))
+
+		wp.Spec.StartupProbe = &corev1.Probe{
+			Handler:             corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(InternalHTTPPort)}},
+			InitialDelaySeconds: 30,
+			FailureThreshold:    60,
+			PeriodSeconds:       15,
+		}
+		Expect(wp.WebPodTemplateSpec().Spec.Containers[0].StartupProbe).To(Equal(wp.Spec.StartupProbe))
+		Expect(wp.startupBudgetSeconds()).To(Equal(int32(930)))
+	})
+
+	It("should use the probe scheme for the default probes", func() {
+		wp.Spec.ProbeScheme = corev1.URISchemeHTTPS
+		spec := wp.WebPodTemplateSpec()
+
+		Expect(spec.Spec.Containers[0].ReadinessProbe.HTTPGet.Scheme).To(Equal(corev1.URISchemeHTTPS))
+		Expect(spec.Spec.Containers[0].LivenessProbe.HTTPGet.Scheme).To(Equal(corev1.URISchemeHTTPS))
+		Expect(spec.Spec.Containers[0].StartupProbe.HTTPGet.Scheme).To(Equal(corev1.URISchemeHTTPS))
+	})
+
+	It("should add the probe headers to the default probes", func() {
+		wp.Spec.ProbeHeaders = []corev1.HTTPHeader{{Name: "X-Health-Check", Value: "true"}}
+		c := wp.WebPodTemplateSpec().Spec.Containers[0]
+
+		Expect(c.ReadinessProbe.HTTPGet.HTTPHeaders).To(Equal([]corev1.HTTPHeader{
+			{Name: "Host", Value: wp.MainDomain()},
+			{Name: "X-Health-Check", Value: "true"},
+		}))
+		Expect(c.LivenessProbe.HTTPGet.HTTPHeaders).To(Equal(wp.Spec.ProbeHeaders))
+
+		wp.Spec.ProbeHeaders = append(wp.Spec.ProbeHeaders, corev1.HTTPHeader{Name: "host", Value: "probe.local"})
+		Expect(wp.readinessProbe().HTTPGet.HTTPHeaders).To(Equal(wp.Spec.ProbeHeaders))
+
+		wp.Spec.MultiRouteReadiness = true
+		Expect(wp.readinessProbe().Exec.Command[2]).To(ContainSubstring(
+			fmt.Sprintf("-H 'Host: %s' -H 'X-Health-Check: true' 'http", wp.MainDomain())))
+		Expect(wp.readinessProbe().Exec.Command[2]).ToNot(ContainSubstring("probe.local"))
+	})
+
+	It("should pre-sync the media files into a local cache when configured", func() {
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			S3VolumeSource: &wordpressv1alpha1.S3VolumeSource{Bucket: "media"},
+			PreSync:        &wordpressv1alpha1.MediaPreSyncSpec{Prefix: "2021"},
+		}
+		wp.SetDefaults()
+		Expect(wp.ValidateMediaVolume()).To(Succeed())
+
+		spec := wp.WebPodTemplateSpec().Spec
+		presync := spec.InitContainers[len(spec.InitContainers)-1]
+		Expect(presync.Name).To(Equal("media-presync"))
+		Expect(presync.Image).To(Equal(defaultMediaPreSyncImage))
+		Expect(presync.Env).To(ContainElement(corev1.EnvVar{Name: "STACK_MEDIA_BUCKET", Value: "s3://media"}))
+		Expect(presync.Env).To(ContainElement(corev1.EnvVar{Name: "MEDIA_PRESYNC_PREFIX", Value: "2021"}))
+		Expect(presync.Env).To(ContainElement(corev1.EnvVar{Name: "MEDIA_PRESYNC_TIMEOUT", Value: "300"}))
+
+		Expect(presync.EnvFrom).To(Equal(spec.Containers[0].EnvFrom))
+
+		mount := corev1.VolumeMount{Name: mediaCacheVolume, MountPath: defaultMediaPreSyncMountPath}
+		Expect(presync.VolumeMounts).To(ContainElement(mount))
+		Expect(spec.Containers[0].VolumeMounts).To(ContainElement(mount))
+		Expect(spec.Volumes).To(ContainElement(corev1.Volume{
+			Name:         mediaCacheVolume,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}))
+
+		for _, c := range wp.JobPodTemplateSpec().Spec.InitContainers {
+			Expect(c.Name).ToNot(Equal("media-presync"))
+		}
+
+		// file credentials are mounted like in the wordpress container
+		credentials := corev1.VolumeMount{Name: "aws-config", MountPath: "/etc/aws", ReadOnly: true}
+		wp.Spec.VolumeMounts = []corev1.VolumeMount{credentials}
+		wp.Spec.Env = []corev1.EnvVar{{Name: "AWS_CONFIG_FILE", Value: "/etc/aws/config"}}
+		wp.Spec.EnvFrom = []corev1.EnvFromSource{
+			{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "aws"}}},
+		}
+		spec = wp.WebPodTemplateSpec().Spec
+		presync = spec.InitContainers[len(spec.InitContainers)-1]
+		Expect(presync.VolumeMounts).To(ContainElement(credentials))
+		Expect(presync.Env).To(ContainElement(wp.Spec.Env[0]))
+		Expect(presync.EnvFrom).To(Equal(spec.Containers[0].EnvFrom))
+		Expect(presync.EnvFrom).To(ContainElement(wp.Spec.EnvFrom[0]))
+
+		wp.Spec.MediaVolumeSpec.PreSync.Prefix = "../other"
+		Expect(wp.ValidateMediaVolume()).To(MatchError(errMediaPreSync))
+
+		wp.Spec.MediaVolumeSpec.PreSync.Prefix = ""
+		wp.Spec.MediaVolumeSpec.S3VolumeSource = nil
+		wp.Spec.MediaVolumeSpec.EmptyDir = &corev1.EmptyDirVolumeSource{}
+		Expect(wp.ValidateMediaVolume()).To(MatchError(errMediaPreSync))
+	})
+
+	It("should require the node architecture when configured", func() {
+		Expect(wp.WebPodTemplateSpec().Spec.Affinity).To(BeNil())
+
+		arch := corev1.NodeSelectorRequirement{
+			Key:      "kubernetes.io/arch",
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{"amd64"},
+		}
+		zone := corev1.NodeSelectorRequirement{
+			Key:      "topology.kubernetes.io/zone",
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{"a"},
+		}
+
+		wp.Spec.Architecture = "amd64"
+		terms := wp.WebPodTemplateSpec().Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		Expect(terms).To(Equal([]corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{arch}}}))
+
+		wp.Spec.Affinity = &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{MatchExpressions: []corev1.NodeSelectorRequirement{zone}},
+						{MatchFields: []corev1.NodeSelectorRequirement{{
+							Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node"},
+						}}},
+					},
+				},
+			},
+			PodAntiAffinity: &corev1.PodAntiAffinity{},
+		}
+		affinity := wp.JobPodTemplateSpec().Spec.Affinity
+		Expect(affinity.PodAntiAffinity).ToNot(BeNil())
+		terms = affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		Expect(terms).To(HaveLen(2))
+		Expect(terms[0].MatchExpressions).To(Equal([]corev1.NodeSelectorRequirement{zone, arch}))
+		Expect(terms[1].MatchExpressions).To(Equal([]corev1.NodeSelectorRequirement{arch}))
+		Expect(wp.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions).To(HaveLen(1))
+	})
+
+	It("should tune the default probes timing when configured", func() {
+		Expect(wp.ValidateProbeDefaults()).To(Succeed())
+
+		// the untuned defaults are kept as is
+		wp.Spec.ProbeDefaults = &wordpressv1alpha1.ProbeDefaultsSpec{}
+		Expect(wp.ValidateProbeDefaults()).To(Succeed())
+
+		// the default 30s timeout is checked against the tuned timing
+		wp.Spec.ProbeDefaults = &wordpressv1alpha1.ProbeDefaultsSpec{FailureThreshold: 5}
+		Expect(wp.ValidateProbeDefaults()).To(MatchError(errProbeDefaults))
+		wp.Spec.ProbeDefaults = &wordpressv1alpha1.ProbeDefaultsSpec{PeriodSeconds: 8}
+		Expect(wp.ValidateProbeDefaults()).To(MatchError(errProbeDefaults))
+		wp.Spec.ProbeDefaults = &wordpressv1alpha1.ProbeDefaultsSpec{PeriodSeconds: 10}
+		Expect(wp.ValidateProbeDefaults()).To(MatchError(errProbeDefaults))
+
+		wp.Spec.ProbeDefaults.TimeoutSeconds = 15
+		Expect(wp.ValidateProbeDefaults()).To(Succeed())
+
+		c := wp.WebPodTemplateSpec().Spec.Containers[0]
+		for _, probe := range []*corev1.Probe{c.ReadinessProbe, c.LivenessProbe} {
+			Expect(probe.TimeoutSeconds).To(Equal(int32(15)))
+			Expect(probe.PeriodSeconds).To(Equal(int32(10)))
+			Expect(probe.FailureThreshold).To(Equal(int32(3)))
+		}
+		Expect(c.StartupProbe.TimeoutSeconds).To(Equal(int32(5)))
+	})
+
+	It("should check every route for readiness when configured", func() {
+		wp.Spec.MultiRouteReadiness = true
+		wp.Spec.Routes = []wordpressv1alpha1.RouteSpec{
+			{Domain: "example.com"},
+			{Domain: "example.org", Path: "/blog"},
+		}
+
+		probe := wp.WebPodTemplateSpec().Spec.Containers[0].ReadinessProbe
+		Expect(probe.HTTPGet).To(BeNil())
+		Expect(probe.Exec.Command).To(Equal([]string{"/bin/sh", "-c", "set -e ; " +
+			"curl -sS -f -o /dev/null -H 'Host: example.com' 'http://127.0.0.1:8080/' ; " +
+			"curl -sS -f -o /dev/null -H 'Host: example.org' 'http://127.0.0.1:8080/blog'",
+		}))
+
+		wp.Spec.ProbeScheme = corev1.URISchemeHTTPS
+		probe = wp.WebPodTemplateSpec().Spec.Containers[0].ReadinessProbe
+		Expect(probe.Exec.Command[2]).To(ContainSubstring("curl -sS -f -k -o /dev/null -H 'Host: example.com' 'https://127.0.0.1:8080/'"))
+	})
+
+	It("should check the media storage for readiness when configured", func() {
+		wp.Spec.MediaReadinessCheck = &wordpressv1alpha1.MediaReadinessCheckSpec{}
+		wp.SetDefaults()
+		Expect(wp.WebPodTemplateSpec().Spec.Containers[0].ReadinessProbe.HTTPGet).ToNot(BeNil())
+
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			GCSVolumeSource: &wordpressv1alpha1.GCSVolumeSource{Bucket: "test-bucket"},
+		}
+		probe := wp.WebPodTemplateSpec().Spec.Containers[0].ReadinessProbe
+		Expect(probe.HTTPGet).To(BeNil())
+		Expect(probe.Exec.Command).To(Equal([]string{"/bin/sh", "-c", "set -e ; " +
+			`'wp' 'eval' 'exit(false === @opendir(getenv('\''STACK_MEDIA_BUCKET'\'')) ? 1 : 0);' ; ` +
+			"curl -sS -f -o /dev/null -H 'Host: test.com' 'http://127.0.0.1:8080/'",
+		}))
+
+		wp.Spec.MediaReadinessCheck.Command = []string{"/usr/local/bin/media-check"}
+		wp.Spec.MultiRouteReadiness = true
+		wp.Spec.Routes = []wordpressv1alpha1.RouteSpec{
+			{Domain: "example.com"},
+			{Domain: "example.org", Path: "/blog"},
+		}
+		probe = wp.WebPodTemplateSpec().Spec.Containers[0].ReadinessProbe
+		Expect(probe.Exec.Command).To(Equal([]string{"/bin/sh", "-c", "set -e ; " +
+			"'/usr/local/bin/media-check' ; " +
+			"curl -sS -f -o /dev/null -H 'Host: example.com' 'http://127.0.0.1:8080/' ; " +
+			"curl -sS -f -o /dev/null -H 'Host: example.org' 'http://127.0.0.1:8080/blog'",
+		}))
+	})
+
 	It("should give me the custom readiness probe specified in the Wordpress resource", func() {
 		probe := corev1.Probe{
 			Handler: corev1.Handler{
@@ -260,7 +753,1686 @@ var _ = Describe("Web pod spec", func() {
 		Expect(*spec.Spec.Containers[0].LivenessProbe).To(Equal(probe))
 	})
 
-})
+	It("should leave sidecars untouched by default", func() {
+		wp.Spec.Sidecars = []corev1.Container{{Name: "sidecar"}}
+		spec := wp.WebPodTemplateSpec()
+
+		Expect(spec.Spec.Containers).To(HaveLen(2))
+		Expect(spec.Spec.Containers[1].SecurityContext).To(BeNil())
+	})
+
+	It("should harden sidecars without a security context", func() {
+		runAsUser := int64(1337)
+		wp.Spec.HardenSidecars = true
+		wp.Spec.Sidecars = []corev1.Container{
+			{Name: "sidecar"},
+			{Name: "mesh", SecurityContext: &corev1.SecurityContext{RunAsUser: &runAsUser}},
+		}
+		spec := wp.WebPodTemplateSpec()
+
+		Expect(spec.Spec.Containers).To(HaveLen(3))
+		Expect(*spec.Spec.Containers[1].SecurityContext.RunAsNonRoot).To(BeTrue())
+		Expect(*spec.Spec.Containers[1].SecurityContext.AllowPrivilegeEscalation).To(BeFalse())
+		Expect(spec.Spec.Containers[2].SecurityContext).To(Equal(&corev1.SecurityContext{RunAsUser: &runAsUser}))
+		Expect(wp.Spec.Sidecars[0].SecurityContext).To(BeNil())
+	})
+
+	It("should share a socket volume between the wordpress container and sidecars", func() {
+		wp.Spec.Sidecars = []corev1.Container{{Name: "nginx"}, {Name: "exporter"}}
+		wp.Spec.SharedSocketVolume = &wordpressv1alpha1.SharedSocketVolumeSpec{}
+		wp.SetDefaults()
+		Expect(wp.ValidateSharedSocketVolume()).To(Succeed())
+
+		mount := corev1.VolumeMount{Name: "shared-socket", MountPath: "/var/run/sockets"}
+		spec := wp.WebPodTemplateSpec()
+
+		Expect(spec.Spec.Volumes).To(ContainElement(corev1.Volume{
+			Name:         "shared-socket",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}))
+		for _, c := range spec.Spec.Containers {
+			Expect(c.VolumeMounts).To(ContainElement(mount))
+		}
+		Expect(wp.Spec.Sidecars[0].VolumeMounts).To(BeEmpty())
+
+		for _, p := range []string{"/var/log", "/var/log/php", "/var", "/etc/podinfo"} {
+			wp.Spec.SharedSocketVolume.MountPath = p
+			Expect(wp.ValidateSharedSocketVolume()).To(MatchError(errSharedSocketPath))
+		}
+	})
+
+	It("should render secrets with a vault agent when configured", func() {
+		wp.Spec.VaultAgent = &wordpressv1alpha1.VaultAgentSpec{
+			ConfigMapName: "vault-agent",
+			Env:           []corev1.EnvVar{{Name: "VAULT_ADDR", Value: "https://vault:8200"}},
+		}
+		wp.SetDefaults()
+
+		spec := wp.WebPodTemplateSpec().Spec
+		agent := spec.InitContainers[0]
+		Expect(agent.Name).To(Equal("vault-agent"))
+		Expect(agent.Image).To(Equal(defaultVaultAgentImage))
+		Expect(agent.Args).To(Equal([]string{"agent", "-config=/vault/config/agent.hcl", "-exit-after-auth"}))
+		Expect(agent.Env).To(Equal(wp.Spec.VaultAgent.Env))
+		Expect(agent.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "vault-secrets", MountPath: "/vault/secrets"}))
+
+		Expect(spec.Volumes).To(ContainElement(corev1.Volume{
+			Name: "vault-secrets",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+			},
+		}))
+		Expect(spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name: "vault-secrets", MountPath: "/vault/secrets", ReadOnly: true,
+		}))
+		Expect(spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "VAULT_SECRETS_FILE", Value: "/vault/secrets/secrets"}))
+
+		wp.Spec.Files = []wordpressv1alpha1.FileMount{
+			{Path: "/vault/secrets/wp-config.php", SecretKeyRef: &corev1.SecretKeySelector{Key: "wp-config.php"}},
+		}
+		Expect(wp.ValidateFiles()).To(MatchError(errInvalidFilePath))
+	})
+
+	It("should mount pod info when enabled", func() {
+		spec := wp.WebPodTemplateSpec()
+		Expect(spec.Spec.Volumes).ToNot(ContainElement(wp.podInfoVolume()))
+
+		wp.Spec.ExposePodInfo = true
+		spec = wp.WebPodTemplateSpec()
+
+		Expect(spec.Spec.Volumes).To(ContainElement(wp.podInfoVolume()))
+		Expect(spec.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      "podinfo",
+			MountPath: "/etc/podinfo",
+			ReadOnly:  true,
+		}))
+	})
+
+	It("should run the post-clone command in the git clone container", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{GitRef: "main"},
+		}
+		git := wp.gitCloneContainer()
+		Expect(git.Args).To(Equal([]string{"/bin/bash", "-c", gitCloneScript}))
+
+		wp.Spec.CodeVolumeSpec.GitDir.PostCloneCommand = []string{"composer", "install", "--no-dev"}
+		git = wp.gitCloneContainer()
+		Expect(git.Args).To(Equal([]string{"/bin/bash", "-c", gitCloneScript, "git-clone", "composer", "install", "--no-dev"}))
+	})
+
+	It("should set the init images pull policy when configured", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{GitRef: "main"},
+		}
+		Expect(wp.prepareVolumesContainer().ImagePullPolicy).To(BeEmpty())
+		Expect(wp.gitCloneContainer().ImagePullPolicy).To(BeEmpty())
+
+		wp.Spec.InitImagePullPolicy = corev1.PullAlways
+		Expect(wp.prepareVolumesContainer().ImagePullPolicy).To(Equal(corev1.PullAlways))
+		Expect(wp.gitCloneContainer().ImagePullPolicy).To(Equal(corev1.PullAlways))
+	})
+
+	It("should check out the default branch when no git reference is set", func() {
+		if _, err := exec.LookPath("git"); err != nil {
+			Skip("git is not installed")
+		}
+
+		dir, err := os.MkdirTemp("", "git-clone")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{Repository: initGitRepo(dir, "trunk")},
+		}
+		git := wp.gitCloneContainer()
+		_, found := lookupEnvVar("GIT_CLONE_REF", git.Env)
+		Expect(found).To(BeFalse())
+
+		src := filepath.Join(dir, "src")
+		out, err := runGitClone(git, src)
+		Expect(err).ToNot(HaveOccurred(), out)
+
+		branch, err := exec.Command("git", "-C", src, "rev-parse", "--abbrev-ref", "HEAD").Output()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.TrimSpace(string(branch))).To(Equal("trunk"))
+	})
+
+	It("should checkout a detached HEAD when configured", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{GitRef: "main"},
+		}
+		spec := wp.WebPodTemplateSpec()
+		_, found := lookupEnvVar("GIT_CLONE_DETACHED", spec.Spec.InitContainers[1].Env)
+		Expect(found).To(BeFalse())
+
+		wp.Spec.CodeVolumeSpec.GitDir.Detached = true
+		spec = wp.WebPodTemplateSpec()
+		e, found := lookupEnvVar("GIT_CLONE_DETACHED", spec.Spec.InitContainers[1].Env)
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("true"))
+	})
+
+	It("should clone atomically when configured", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{GitRef: "main"},
+		}
+		spec := wp.WebPodTemplateSpec()
+		_, found := lookupEnvVar("GIT_CLONE_ATOMIC", spec.Spec.InitContainers[1].Env)
+		Expect(found).To(BeFalse())
+
+		wp.Spec.CodeVolumeSpec.GitDir.AtomicClone = true
+		spec = wp.WebPodTemplateSpec()
+		e, found := lookupEnvVar("GIT_CLONE_ATOMIC", spec.Spec.InitContainers[1].Env)
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("true"))
+	})
+
+	It("should skip the clone of populated code when configured", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{GitRef: "main"},
+		}
+		spec := wp.WebPodTemplateSpec()
+		_, found := lookupEnvVar("GIT_CLONE_SKIP_IF_POPULATED", spec.Spec.InitContainers[1].Env)
+		Expect(found).To(BeFalse())
+		_, found = lookupEnvVar("GIT_CLONE_RECLONE", spec.Spec.InitContainers[1].Env)
+		Expect(found).To(BeFalse())
+
+		wp.Spec.CodeVolumeSpec.GitDir.SkipIfPopulated = true
+		wp.Annotations = map[string]string{ForceRecloneAnnotation: "2"}
+		spec = wp.WebPodTemplateSpec()
+		e, found := lookupEnvVar("GIT_CLONE_SKIP_IF_POPULATED", spec.Spec.InitContainers[1].Env)
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("true"))
+		e, found = lookupEnvVar("GIT_CLONE_RECLONE", spec.Spec.InitContainers[1].Env)
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("2"))
+	})
+
+	It("should clone populated code again unless the clone completed", func() {
+		if _, err := exec.LookPath("git"); err != nil {
+			Skip("git is not installed")
+		}
+
+		dir, err := os.MkdirTemp("", "git-clone")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{
+				Repository:      initGitRepo(dir, "main"),
+				GitRef:          "main",
+				SkipIfPopulated: true,
+			},
+		}
+		src := filepath.Join(dir, "src")
+
+		// a failed post-clone command leaves the code unpopulated
+		wp.Spec.CodeVolumeSpec.GitDir.PostCloneCommand = []string{"false"}
+		out, err := runGitClone(wp.gitCloneContainer(), src)
+		Expect(err).To(HaveOccurred(), out)
+
+		wp.Spec.CodeVolumeSpec.GitDir.PostCloneCommand = nil
+		out, err = runGitClone(wp.gitCloneContainer(), src)
+		Expect(err).ToNot(HaveOccurred(), out)
+		Expect(out).ToNot(ContainSubstring("skipping the clone"))
+
+		out, err = runGitClone(wp.gitCloneContainer(), src)
+		Expect(err).ToNot(HaveOccurred(), out)
+		Expect(out).To(ContainSubstring("skipping the clone"))
+
+		wp.Annotations = map[string]string{ForceRecloneAnnotation: "1"}
+		out, err = runGitClone(wp.gitCloneContainer(), src)
+		Expect(err).ToNot(HaveOccurred(), out)
+		Expect(out).ToNot(ContainSubstring("skipping the clone"))
+
+		// removing the annotation is a new reclone request as well
+		wp.Annotations = nil
+		out, err = runGitClone(wp.gitCloneContainer(), src)
+		Expect(err).ToNot(HaveOccurred(), out)
+		Expect(out).ToNot(ContainSubstring("skipping the clone"))
+
+		out, err = runGitClone(wp.gitCloneContainer(), src)
+		Expect(err).ToNot(HaveOccurred(), out)
+		Expect(out).To(ContainSubstring("skipping the clone"))
+	})
+
+	It("should mount the git SSH key secret as a file", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{
+				SSHKeySecret: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "git-key"},
+					Key:                  "id_rsa",
+				},
+			},
+		}
+		spec := wp.WebPodTemplateSpec()
+
+		Expect(spec.Spec.Volumes).To(ContainElement(wp.gitSSHKeyVolume()))
+		Expect(*spec.Spec.SecurityContext.FSGroup).To(Equal(wwwDataGroupID))
+
+		git := spec.Spec.InitContainers[1]
+		Expect(git.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      "git-ssh-key",
+			MountPath: "/var/run/presslabs.org/git/ssh",
+			ReadOnly:  true,
+		}))
+		e, found := lookupEnvVar("SSH_KEY_FILE", git.Env)
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("/var/run/presslabs.org/git/ssh/id_rsa"))
+	})
+
+	It("should mount the git signature verification keys when configured", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{
+				VerifySignature: &wordpressv1alpha1.GitSignatureVerification{
+					PublicKeySecret: corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "release-keys"},
+						Key:                  "keys.asc",
+					},
+				},
+			},
+		}
+		spec := wp.WebPodTemplateSpec()
+
+		Expect(spec.Spec.Volumes).To(ContainElement(wp.gitVerifyKeyVolume()))
+
+		git := spec.Spec.InitContainers[1]
+		Expect(git.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      "git-verify-key",
+			MountPath: "/var/run/presslabs.org/git/gpg",
+			ReadOnly:  true,
+		}))
+		e, found := lookupEnvVar("GIT_VERIFY_KEY_FILE", git.Env)
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("/var/run/presslabs.org/git/gpg/pubkeys.asc"))
+	})
+
+	It("should clone the config from a separate git repository when configured", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{Repository: "https://github.com/bitpoke/stack-example-wordpress.git"},
+			ConfigGitDir: &wordpressv1alpha1.ConfigGitVolumeSource{
+				Repository: "https://git.example.com/blog/config.git",
+				GitRef:     "v1",
+			},
+		}
+		wp.SetDefaults()
+		Expect(wp.ValidateCodeVolume()).To(Succeed())
+
+		spec := wp.WebPodTemplateSpec().Spec
+		config := spec.InitContainers[2]
+		Expect(config.Name).To(Equal("git-config"))
+		Expect(spec.InitContainers[1].Name).To(Equal("git"))
+
+		e, _ := lookupEnvVar("GIT_CLONE_URL", config.Env)
+		Expect(e.Value).To(Equal("https://git.example.com/blog/config.git"))
+		e, _ = lookupEnvVar("GIT_CLONE_REF", config.Env)
+		Expect(e.Value).To(Equal("v1"))
+		e, _ = lookupEnvVar("SRC_DIR", config.Env)
+		Expect(e.Value).To(Equal(configSrcMountPath))
+
+		Expect(spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      configVolumeName,
+			MountPath: configMountPath,
+			ReadOnly:  true,
+		}))
+		Expect(spec.Containers[0].VolumeMounts).ToNot(ContainElement(corev1.VolumeMount{
+			Name:      codeVolumeName,
+			MountPath: configMountPath,
+			ReadOnly:  true,
+			SubPath:   "config",
+		}))
+
+		wp.Spec.CodeVolumeSpec.MountPath = "/app"
+		Expect(wp.ValidateCodeVolume()).To(MatchError(errConfigMountPath))
+	})
+
+	It("should mount the git CA bundle secret as a file", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{},
+		}
+		_, found := lookupEnvVar("GIT_SSL_CAINFO", wp.WebPodTemplateSpec().Spec.InitContainers[1].Env)
+		Expect(found).To(BeFalse())
+
+		wp.Spec.CodeVolumeSpec.GitDir.CABundleSecret = &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "git-ca"},
+			Key:                  "ca.pem",
+		}
+		spec := wp.WebPodTemplateSpec()
+
+		Expect(spec.Spec.Volumes).To(ContainElement(wp.gitCABundleVolume()))
+
+		git := spec.Spec.InitContainers[1]
+		Expect(git.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      "git-ca-bundle",
+			MountPath: "/var/run/presslabs.org/git/ca",
+			ReadOnly:  true,
+		}))
+		e, found := lookupEnvVar("GIT_SSL_CAINFO", git.Env)
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("/var/run/presslabs.org/git/ca/ca.crt"))
+	})
+
+	It("should apply the default resources when none are specified", func() {
+		defaultResources := options.DefaultResources
+		defer func() { options.DefaultResources = defaultResources }()
+
+		options.DefaultResources = corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("100m"),
+			},
+		}
+		spec := wp.WebPodTemplateSpec()
+		Expect(spec.Spec.Containers[0].Resources).To(Equal(options.DefaultResources))
+
+		wp.Spec.Resources = corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("128Mi"),
+			},
+		}
+		spec = wp.WebPodTemplateSpec()
+		Expect(spec.Spec.Containers[0].Resources).To(Equal(wp.Spec.Resources))
+	})
+
+	DescribeTable("Should set enableServiceLinks",
+		func(f func() (func() corev1.PodTemplateSpec, *Wordpress)) {
+			podSpec, w := f()
+			Expect(podSpec().Spec.EnableServiceLinks).To(BeNil())
+
+			enableServiceLinks := false
+			w.Spec.EnableServiceLinks = &enableServiceLinks
+			Expect(*podSpec().Spec.EnableServiceLinks).To(BeFalse())
+		},
+		Entry("for web pod", func() (func() corev1.PodTemplateSpec, *Wordpress) {
+			return wp.WebPodTemplateSpec, wp
+		}),
+		Entry("for job pod", func() (func() corev1.PodTemplateSpec, *Wordpress) {
+			return func() corev1.PodTemplateSpec { return wp.JobPodTemplateSpec("test") }, wp
+		}),
+	)
+
+	DescribeTable("Should set the pod overhead",
+		func(f func() (func() corev1.PodTemplateSpec, *Wordpress)) {
+			podSpec, w := f()
+			Expect(podSpec().Spec.Overhead).To(BeNil())
+
+			w.Spec.PodOverhead = corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("120Mi"),
+			}
+			Expect(podSpec().Spec.Overhead).To(Equal(w.Spec.PodOverhead))
+		},
+		Entry("for web pod", func() (func() corev1.PodTemplateSpec, *Wordpress) {
+			return wp.WebPodTemplateSpec, wp
+		}),
+		Entry("for job pod", func() (func() corev1.PodTemplateSpec, *Wordpress) {
+			return func() corev1.PodTemplateSpec { return wp.JobPodTemplateSpec("test") }, wp
+		}),
+	)
+
+	It("should force S3 path style addressing when configured", func() {
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			S3VolumeSource: &wordpressv1alpha1.S3VolumeSource{
+				Bucket: "test-bucket",
+			},
+		}
+		_, found := lookupEnvVar("S3_FORCE_PATH_STYLE", wp.ContainerEnv())
+		Expect(found).To(BeFalse())
+
+		wp.Spec.MediaVolumeSpec.S3VolumeSource.ForcePathStyle = true
+		e, found := lookupEnvVar("S3_FORCE_PATH_STYLE", wp.ContainerEnv())
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("true"))
+	})
+
+	It("should set the media objects ACL when configured", func() {
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			S3VolumeSource: &wordpressv1alpha1.S3VolumeSource{
+				Bucket: "test-bucket",
+			},
+		}
+		_, found := lookupEnvVar("STACK_MEDIA_ACL", wp.ContainerEnv())
+		Expect(found).To(BeFalse())
+
+		wp.Spec.MediaVolumeSpec.S3VolumeSource.ObjectACL = "public-read"
+		e, found := lookupEnvVar("STACK_MEDIA_ACL", wp.ContainerEnv())
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("public-read"))
+
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			GCSVolumeSource: &wordpressv1alpha1.GCSVolumeSource{
+				Bucket:        "test-bucket",
+				PredefinedACL: "projectPrivate",
+			},
+		}
+		e, found = lookupEnvVar("STACK_MEDIA_ACL", wp.ContainerEnv())
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("projectPrivate"))
+	})
+
+	It("should set the S3 region when configured", func() {
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			S3VolumeSource: &wordpressv1alpha1.S3VolumeSource{
+				Bucket: "test-bucket",
+			},
+		}
+		_, found := lookupEnvVar("AWS_REGION", wp.ContainerEnv())
+		Expect(found).To(BeFalse())
+
+		wp.Spec.MediaVolumeSpec.S3VolumeSource.Env = []corev1.EnvVar{{Name: "REGION", Value: "us-east-1"}}
+		e, found := lookupEnvVar("AWS_REGION", wp.ContainerEnv())
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("us-east-1"))
+
+		wp.Spec.MediaVolumeSpec.S3VolumeSource.Env = nil
+		wp.Spec.MediaVolumeSpec.S3VolumeSource.Region = "eu-central-1"
+		e, found = lookupEnvVar("AWS_REGION", wp.ContainerEnv())
+		Expect(found).To(BeTrue())
+		Expect(e.Value).To(Equal("eu-central-1"))
+	})
+
+	It("should generate the canary pod template", func() {
+		wp.Spec.Canary = &wordpressv1alpha1.CanarySpec{Image: "canary:latest"}
+		spec := wp.CanaryPodTemplateSpec()
+
+		Expect(spec.Spec.Containers[0].Image).To(Equal("canary:latest"))
+		Expect(spec.ObjectMeta.Labels).To(HaveKeyWithValue("wordpress.presslabs.org/track", "canary"))
+		Expect(wp.WebPodTemplateSpec().Spec.Containers[0].Image).To(Equal(options.WordpressRuntimeImage))
+	})
+
+	It("should default writable host path volumes to DirectoryOrCreate", func() {
+		hostPathFile := corev1.HostPathFile
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			HostPath: &corev1.HostPathVolumeSource{Path: "/code"},
+		}
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			HostPath: &corev1.HostPathVolumeSource{Path: "/media", Type: &hostPathFile},
+		}
+		wp.SetDefaults()
+
+		Expect(*wp.codeVolume().HostPath.Type).To(Equal(corev1.HostPathDirectoryOrCreate))
+		Expect(*wp.mediaVolume().HostPath.Type).To(Equal(corev1.HostPathFile))
+	})
+
+	It("should not default read-only host path volumes type", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			ReadOnly: true,
+			HostPath: &corev1.HostPathVolumeSource{Path: "/code"},
+		}
+		wp.SetDefaults()
+
+		Expect(wp.codeVolume().HostPath.Type).To(BeNil())
+	})
+
+	It("should set shareProcessNamespace on web pods", func() {
+		Expect(wp.WebPodTemplateSpec().Spec.ShareProcessNamespace).To(BeNil())
+
+		shareProcessNamespace := true
+		wp.Spec.ShareProcessNamespace = &shareProcessNamespace
+		Expect(*wp.WebPodTemplateSpec().Spec.ShareProcessNamespace).To(BeTrue())
+		Expect(wp.JobPodTemplateSpec().Spec.ShareProcessNamespace).To(BeNil())
+	})
+
+	It("should allow overriding the main container names", func() {
+		Expect(wp.WebPodTemplateSpec().Spec.Containers[0].Name).To(Equal("wordpress"))
+		Expect(wp.JobPodTemplateSpec().Spec.Containers[0].Name).To(Equal("wp-cli"))
+
+		wp.Spec.ContainerName = "app"
+		wp.Spec.CLIContainerName = "cli"
+		Expect(wp.WebPodTemplateSpec().Spec.Containers[0].Name).To(Equal("app"))
+		Expect(wp.JobPodTemplateSpec().Spec.Containers[0].Name).To(Equal("cli"))
+	})
+
+	It("should run migrations in web pods only", func() {
+		Expect(wp.WebPodTemplateSpec().Spec.InitContainers).To(HaveLen(0))
+
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{
+				Repository: "https://github.com/bitpoke/stack-example-wordpress.git",
+			},
+		}
+		wp.Spec.Migrations = &wordpressv1alpha1.MigrationsSpec{
+			Command: []string{"wp", "core", "update-db"},
+			Env: []corev1.EnvVar{
+				{Name: "MIGRATIONS_ENV", Value: "value"},
+			},
+		}
+
+		spec := wp.WebPodTemplateSpec().Spec
+		containers := spec.InitContainers
+		Expect(containers[len(containers)-2].Name).To(Equal("git"))
+
+		migrations := containers[len(containers)-1]
+		Expect(migrations.Name).To(Equal("migrations"))
+		Expect(migrations.Image).To(Equal(wp.Spec.Image))
+		Expect(migrations.Command).To(Equal([]string{"wp", "core", "update-db"}))
+		Expect(migrations.VolumeMounts).To(Equal(spec.Containers[0].VolumeMounts))
+		Expect(migrations.EnvFrom).To(Equal(spec.Containers[0].EnvFrom))
+		Expect(migrations.Env).To(ContainElement(corev1.EnvVar{Name: "MIGRATIONS_ENV", Value: "value"}))
+		Expect(migrations.TerminationMessagePolicy).To(Equal(corev1.TerminationMessageFallbackToLogsOnError))
+
+		for _, c := range wp.JobPodTemplateSpec().Spec.InitContainers {
+			Expect(c.Name).ToNot(Equal("migrations"))
+		}
+	})
+
+	It("should mount files from configmaps and secrets", func() {
+		wp.Spec.Files = []wordpressv1alpha1.FileMount{
+			{
+				Path: "/usr/local/etc/php/conf.d/zz-custom.ini",
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "php-config"},
+					Key:                  "php.ini",
+				},
+			},
+			{
+				Path: "/etc/nginx/conf.d/custom.conf",
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "nginx-config"},
+					Key:                  "nginx.conf",
+				},
+			},
+		}
+		Expect(wp.ValidateFiles()).To(Succeed())
+
+		spec := wp.WebPodTemplateSpec().Spec
+		Expect(spec.Volumes).To(ContainElement(corev1.Volume{
+			Name: "file-0",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "php-config"},
+					Items:                []corev1.KeyToPath{{Key: "php.ini", Path: "php.ini"}},
+				},
+			},
+		}))
+		Expect(spec.Volumes).To(ContainElement(corev1.Volume{
+			Name: "file-1",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: "nginx-config",
+					Items:      []corev1.KeyToPath{{Key: "nginx.conf", Path: "nginx.conf"}},
+				},
+			},
+		}))
+		Expect(spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      "file-0",
+			MountPath: "/usr/local/etc/php/conf.d/zz-custom.ini",
+			SubPath:   "php.ini",
+			ReadOnly:  true,
+		}))
+		Expect(wp.JobPodTemplateSpec().Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      "file-1",
+			MountPath: "/etc/nginx/conf.d/custom.conf",
+			SubPath:   "nginx.conf",
+			ReadOnly:  true,
+		}))
+	})
+
+	DescribeTable("should reject invalid files",
+		func(f wordpressv1alpha1.FileMount) {
+			wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+				MountPath: "/app/web/wp-content",
+				EmptyDir:  &corev1.EmptyDirVolumeSource{},
+			}
+			wp.Spec.Files = append(wp.Spec.Files, f)
+
+			Expect(wp.ValidateFiles()).ToNot(Succeed())
+		},
+		Entry("without a source", wordpressv1alpha1.FileMount{Path: "/etc/file"}),
+		Entry("with both sources", wordpressv1alpha1.FileMount{
+			Path:            "/etc/file",
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "file"},
+			SecretKeyRef:    &corev1.SecretKeySelector{Key: "file"},
+		}),
+		Entry("with a relative path", wordpressv1alpha1.FileMount{
+			Path:            "etc/file",
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "file"},
+		}),
+		Entry("over a reserved mount", wordpressv1alpha1.FileMount{
+			Path:            "/var/log",
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "file"},
+		}),
+		Entry("inside the code volume", wordpressv1alpha1.FileMount{
+			Path:            "/app/web/wp-content/object-cache.php",
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "file"},
+		}),
+	)
+
+	It("should reject duplicate file paths", func() {
+		f := wordpressv1alpha1.FileMount{
+			Path:            "/etc/file",
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "file"},
+		}
+		wp.Spec.Files = []wordpressv1alpha1.FileMount{f, f}
+
+		Expect(wp.ValidateFiles()).ToNot(Succeed())
+	})
+
+	It("should validate route annotations", func() {
+		wp.Spec.Routes = []wordpressv1alpha1.RouteSpec{
+			{
+				Domain: "example.com",
+				Annotations: map[string]string{
+					"nginx.ingress.kubernetes.io/limit-rps": "10",
+				},
+			},
+		}
+		Expect(wp.ValidateRoutes()).To(Succeed())
+
+		wp.Spec.Routes[0].Annotations["invalid key!"] = "value"
+		Expect(wp.ValidateRoutes()).ToNot(Succeed())
+	})
+
+	It("should default replicas to 1", func() {
+		wp.Spec.Replicas = nil
+		wp.SetDefaults()
+		Expect(*wp.Spec.Replicas).To(Equal(int32(1)))
+	})
+
+	It("should raise replicas to the configured floor", func() {
+		defer func(min int32) { options.MinReplicas = min }(options.MinReplicas)
+
+		zero := int32(0)
+		wp.Spec.Replicas = &zero
+		wp.SetDefaults()
+		Expect(*wp.Spec.Replicas).To(Equal(int32(1)))
+		Expect(zero).To(Equal(int32(0)))
+
+		options.MinReplicas = 3
+		two := int32(2)
+		wp.Spec.Replicas = &two
+		wp.SetDefaults()
+		Expect(*wp.Spec.Replicas).To(Equal(int32(3)))
+
+		five := int32(5)
+		wp.Spec.Replicas = &five
+		wp.SetDefaults()
+		Expect(*wp.Spec.Replicas).To(Equal(int32(5)))
+	})
+
+	It("should take the replicas from the configured annotation", func() {
+		three := int32(3)
+		wp.Spec.Replicas = &three
+		wp.Spec.ReplicasFromAnnotation = "scaler.example.com/replicas"
+		wp.SetDefaults()
+		Expect(wp.ValidateReplicas()).To(Succeed())
+		Expect(*wp.Spec.Replicas).To(Equal(int32(3)))
+
+		wp.ObjectMeta.Annotations = map[string]string{"scaler.example.com/replicas": "7"}
+		wp.SetDefaults()
+		Expect(wp.ValidateReplicas()).To(Succeed())
+		Expect(*wp.Spec.Replicas).To(Equal(int32(7)))
+		Expect(three).To(Equal(int32(3)))
+
+		for _, value := range []string{"0", "-1", "two", ""} {
+			wp.ObjectMeta.Annotations["scaler.example.com/replicas"] = value
+			Expect(wp.ValidateReplicas()).ToNot(Succeed())
+		}
+	})
+
+	It("should allow zero replicas for paused sites", func() {
+		zero := int32(0)
+		wp.Spec.Replicas = &zero
+		wp.Spec.Paused = true
+		wp.SetDefaults()
+		Expect(*wp.Spec.Replicas).To(Equal(int32(0)))
+	})
+
+	It("should pull images pinned by tag and digest by digest", func() {
+		digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		wp.Spec.Image = "docker.io/bitpoke/wordpress-runtime:5.8.2@" + digest
+		Expect(wp.ValidateImage()).To(Succeed())
+
+		web := wp.WebPodTemplateSpec()
+		Expect(web.Spec.Containers[0].Image).To(Equal("docker.io/bitpoke/wordpress-runtime@" + digest))
+		Expect(web.Annotations).To(HaveKeyWithValue(ImageTagAnnotation, "5.8.2"))
+
+		job := wp.JobPodTemplateSpec()
+		Expect(job.Spec.Containers[0].Image).To(Equal("docker.io/bitpoke/wordpress-runtime@" + digest))
+		Expect(job.Annotations).To(HaveKeyWithValue(ImageTagAnnotation, "5.8.2"))
+	})
+
+	It("should set the init containers resources from the profile", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{Repository: "https://example.com/site.git"},
+		}
+		wp.SetDefaults()
+		for _, c := range wp.WebPodTemplateSpec().Spec.InitContainers {
+			Expect(c.Resources).To(Equal(corev1.ResourceRequirements{}))
+		}
+
+		custom := corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+		}
+		wp.Spec.InitContainers = []corev1.Container{{Name: "custom", Resources: custom}}
+		wp.Spec.InitResourceProfile = wordpressv1alpha1.InitResourceProfileMedium
+
+		containers := wp.JobPodTemplateSpec().Spec.InitContainers
+		Expect(containers).ToNot(BeEmpty())
+		for _, c := range containers {
+			memory := c.Resources.Limits[corev1.ResourceMemory]
+			switch c.Name {
+			case "custom":
+				Expect(c.Resources).To(Equal(custom))
+			case "git":
+				Expect(memory.String()).To(Equal("1Gi"))
+			default:
+				Expect(memory.String()).To(Equal("512Mi"))
+				Expect(c.Resources.Requests).To(HaveKey(corev1.ResourceCPU))
+			}
+		}
+	})
+
+	It("should record the git reference of the code on pods", func() {
+		Expect(wp.WebPodTemplateSpec().Annotations).ToNot(HaveKey(GitCommitAnnotation))
+
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{Repository: "https://example.com/site.git"},
+		}
+		Expect(wp.WebPodTemplateSpec().Annotations).To(HaveKeyWithValue(GitCommitAnnotation, "HEAD"))
+
+		wp.Spec.CodeVolumeSpec.GitDir.GitRef = "0123456789abcdef0123456789abcdef01234567"
+		Expect(wp.WebPodTemplateSpec().Annotations).To(HaveKeyWithValue(GitCommitAnnotation, wp.Spec.CodeVolumeSpec.GitDir.GitRef))
+
+		wp.Spec.CodeVolumeSpec.GitDir.GitRef = "v1.2.0"
+		Expect(wp.JobPodTemplateSpec().Annotations).To(HaveKeyWithValue(GitCommitAnnotation, "v1.2.0"))
+	})
+
+	It("should leave images without digest untouched", func() {
+		wp.Spec.Image = "localhost:5000/bitpoke/wordpress-runtime:5.8.2"
+		Expect(wp.ValidateImage()).To(Succeed())
+
+		web := wp.WebPodTemplateSpec()
+		Expect(web.Spec.Containers[0].Image).To(Equal(wp.Spec.Image))
+		Expect(web.Annotations).ToNot(HaveKey(ImageTagAnnotation))
+	})
+
+	It("should pull images from the configured registry mirrors", func() {
+		defer func(m map[string]string) { options.RegistryMirrors = m }(options.RegistryMirrors)
+
+		wp.Spec.Sidecars = []corev1.Container{{Name: "nginx", Image: "nginx:1.21"}}
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		}
+		spec := wp.WebPodTemplateSpec().Spec
+		Expect(spec.Containers[1].Image).To(Equal("nginx:1.21"))
+
+		options.RegistryMirrors = map[string]string{
+			"docker.io":                  "registry.local/docker-hub/",
+			"docker.io/bitpoke":          "registry.local/bitpoke",
+			"gcr.io/google-containers/":  "registry.local/gcr",
+			"quay.io/unused-prefix-only": "registry.local/quay",
+		}
+		spec = wp.WebPodTemplateSpec().Spec
+		Expect(spec.Containers[0].Image).To(Equal("registry.local/bitpoke/wordpress-runtime:5.8.2"))
+		Expect(spec.Containers[1].Image).To(Equal("registry.local/docker-hub/library/nginx:1.21"))
+		Expect(spec.InitContainers[0].Image).To(HavePrefix("registry.local/gcr/busybox@sha256:"))
+		Expect(wp.JobPodTemplateSpec().Spec.Containers[0].Image).To(Equal("registry.local/bitpoke/wordpress-runtime:5.8.2"))
+		Expect(wp.Spec.Sidecars[0].Image).To(Equal("nginx:1.21"))
+
+		Expect(mirrorImage("localhost:5000/wordpress:latest")).To(Equal("localhost:5000/wordpress:latest"))
+		Expect(mirrorImage("docker.io/bitpokes/wordpress")).To(Equal("registry.local/docker-hub/bitpokes/wordpress"))
+	})
+
+	DescribeTable("should reject malformed images",
+		func(image string) {
+			wp.Spec.Image = image
+			Expect(wp.ValidateImage()).ToNot(Succeed())
+		},
+		Entry("with a malformed digest", "bitpoke/wordpress-runtime:5.8.2@sha256:xyz"),
+		Entry("with a malformed tag", "bitpoke/wordpress-runtime:-5.8.2"),
+		Entry("with an uppercase name", "Bitpoke/wordpress-runtime"),
+		Entry("with an empty name", ":5.8.2"),
+	)
+
+	It("should clone and serve the code from the worktree subdir", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{
+				Repository:     "https://github.com/bitpoke/stack-example-wordpress.git",
+				WorktreeSubdir: "current",
+			},
+		}
+		wp.SetDefaults()
+
+		spec := wp.WebPodTemplateSpec().Spec
+		containers := spec.InitContainers
+		git := containers[len(containers)-1]
+		Expect(git.Name).To(Equal("git"))
+		Expect(git.Env).To(ContainElement(corev1.EnvVar{Name: "SRC_DIR", Value: codeSrcMountPath + "/current"}))
+
+		mounts := spec.Containers[0].VolumeMounts
+		Expect(mounts).To(ContainElement(corev1.VolumeMount{
+			Name:      codeVolumeName,
+			MountPath: codeSrcMountPath,
+			SubPath:   "current",
+		}))
+		Expect(mounts).To(ContainElement(corev1.VolumeMount{
+			Name:      codeVolumeName,
+			MountPath: defaultCodeMountPath,
+			SubPath:   "current/wp-content",
+		}))
+		Expect(mounts).To(ContainElement(corev1.VolumeMount{
+			Name:      codeVolumeName,
+			MountPath: configMountPath,
+			SubPath:   "current/config",
+			ReadOnly:  true,
+		}))
+	})
+
+	It("should mount media in the git clone container when configured", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{
+				Repository: "https://github.com/bitpoke/stack-example-wordpress.git",
+				MountMedia: true,
+			},
+		}
+		Expect(wp.ValidateCodeVolume()).To(MatchError(errGitMediaMount))
+
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			ReadOnly: true,
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		}
+		Expect(wp.ValidateCodeVolume()).To(MatchError(errGitMediaMount))
+
+		wp.Spec.MediaVolumeSpec.ReadOnly = false
+		Expect(wp.ValidateCodeVolume()).To(Succeed())
+		wp.SetDefaults()
+
+		containers := wp.WebPodTemplateSpec().Spec.InitContainers
+		git := containers[len(containers)-1]
+		Expect(git.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      mediaVolumeName,
+			MountPath: gitMediaMountPath,
+		}))
+		Expect(git.Env).To(ContainElement(corev1.EnvVar{Name: "MEDIA_DIR", Value: gitMediaMountPath}))
+	})
+
+	It("should only allow cloning from the allowed git schemes", func() {
+		defer func(s []string) { options.AllowedGitSchemes = s }(options.AllowedGitSchemes)
+
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{
+				Repository: "https://github.com/bitpoke/stack-example-wordpress.git",
+			},
+		}
+		Expect(wp.ValidateCodeVolume()).To(Succeed())
+
+		options.AllowedGitSchemes = []string{"ssh"}
+		Expect(wp.ValidateCodeVolume()).To(MatchError(errGitSchemeNotAllowed))
+
+		for _, repo := range []string{
+			"ssh://git@github.com/bitpoke/stack-example-wordpress.git",
+			"git@github.com:bitpoke/stack-example-wordpress.git",
+		} {
+			wp.Spec.CodeVolumeSpec.GitDir.Repository = repo
+			Expect(wp.ValidateCodeVolume()).To(Succeed())
+		}
+
+		wp.Spec.CodeVolumeSpec.GitDir.Repository = "/var/lib/git/stack-example-wordpress"
+		Expect(wp.ValidateCodeVolume()).To(MatchError(errGitSchemeNotAllowed))
+	})
+
+	It("should check out a worktree from the git mirror", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{
+				Repository: "https://github.com/bitpoke/stack-example-wordpress.git",
+				Mirror:     true,
+			},
+		}
+		wp.SetDefaults()
+
+		spec := wp.WebPodTemplateSpec().Spec
+		containers := spec.InitContainers
+		git := containers[len(containers)-1]
+		Expect(git.Env).To(ContainElement(corev1.EnvVar{Name: "GIT_CLONE_MIRROR", Value: "true"}))
+		Expect(git.Env).To(ContainElement(corev1.EnvVar{Name: "GIT_MIRROR_DIR", Value: codeSrcMountPath + "/.git-mirror"}))
+		Expect(git.Env).To(ContainElement(corev1.EnvVar{Name: "SRC_DIR", Value: codeSrcMountPath + "/worktree"}))
+		Expect(spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      codeVolumeName,
+			MountPath: codeSrcMountPath,
+			SubPath:   "worktree",
+		}))
+	})
+
+	It("should give each statefulset replica its own media claim", func() {
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			},
+		}
+		Expect(wp.MediaVolumeClaimTemplates()).To(BeEmpty())
+
+		wp.Spec.StatefulSetMode = true
+		claims := wp.MediaVolumeClaimTemplates()
+		Expect(claims).To(HaveLen(1))
+		Expect(claims[0].Name).To(Equal(mediaVolumeName))
+		Expect(claims[0].Spec).To(Equal(*wp.Spec.MediaVolumeSpec.PersistentVolumeClaim))
+
+		for _, v := range wp.StatefulSetPodTemplateSpec().Spec.Volumes {
+			Expect(v.Name).ToNot(Equal(mediaVolumeName))
+		}
+
+		// job pods mount the claim of the first replica
+		claimNames := []string{}
+		for _, v := range wp.JobPodTemplateSpec().Spec.Volumes {
+			if v.PersistentVolumeClaim != nil {
+				claimNames = append(claimNames, v.PersistentVolumeClaim.ClaimName)
+			}
+		}
+		Expect(claimNames).To(ConsistOf("media-" + wp.Name + "-0"))
+	})
+
+	It("should summarize the web pod template", func() {
+		plan := wp.PodPlan()
+		Expect(plan.GitClone).To(BeFalse())
+		Expect(plan.MediaProvider).To(BeEmpty())
+		Expect(plan.ReadinessProbe).To(Equal(ProbePlan{
+			Action:              "HTTP GET :8080/",
+			InitialDelaySeconds: 10,
+			PeriodSeconds:       5,
+			TimeoutSeconds:      30,
+			FailureThreshold:    3,
+		}))
+
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{
+				Repository: "https://github.com/bitpoke/stack-example-wordpress.git",
+			},
+		}
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			GCSVolumeSource: &wordpressv1alpha1.GCSVolumeSource{Bucket: "media"},
+		}
+		wp.Spec.LivenessProbe = &corev1.Probe{
+			Handler: corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(InternalHTTPPort)}},
+		}
+		wp.SetDefaults()
+
+		plan = wp.PodPlan()
+		Expect(plan.InitContainers).To(Equal([]string{"prepare-volumes", "git"}))
+		Expect(plan.PrepareVolumes).To(BeTrue())
+		Expect(plan.GitClone).To(BeTrue())
+		Expect(plan.InstallWP).To(BeFalse())
+		Expect(plan.Volumes).To(HaveKeyWithValue(codeVolumeName, "emptyDir"))
+		Expect(plan.VolumeMounts).To(HaveKeyWithValue(codeSrcMountPath, codeVolumeName))
+		Expect(plan.MediaProvider).To(Equal("gcs"))
+		Expect(plan.LivenessProbe.Custom).To(BeTrue())
+		Expect(plan.LivenessProbe.Action).To(Equal("TCP :8080"))
+	})
+
+	It("should set the termination message policy on wordpress and init containers", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{
+				Repository: "https://github.com/bitpoke/stack-example-wordpress.git",
+			},
+		}
+		wp.SetDefaults()
+
+		spec := wp.WebPodTemplateSpec().Spec
+		Expect(spec.InitContainers).ToNot(BeEmpty())
+		for _, c := range append(spec.InitContainers, spec.Containers[0]) {
+			Expect(c.TerminationMessagePolicy).To(Equal(corev1.TerminationMessageFallbackToLogsOnError))
+		}
+
+		wp.Spec.TerminationMessagePolicy = corev1.TerminationMessageReadFile
+		Expect(wp.JobPodTemplateSpec().Spec.Containers[0].TerminationMessagePolicy).To(Equal(corev1.TerminationMessageReadFile))
+	})
+
+	It("should allow user env to reference the computed env", func() {
+		wp.Spec.Env = []corev1.EnvVar{
+			{Name: "CACHE_PREFIX", Value: "$(STACK_SITE_NAMESPACE)-$(STACK_SITE_NAME)"},
+		}
+
+		env := expandEnv(wp.WebPodTemplateSpec().Spec.Containers[0].Env)
+		Expect(env).To(HaveKeyWithValue("CACHE_PREFIX", fmt.Sprintf("%s-%s", wp.Namespace, wp.Name)))
+	})
+
+	It("should merge the site node selector over the default one", func() {
+		defer func(sel map[string]string) { options.DefaultNodeSelector = sel }(options.DefaultNodeSelector)
+
+		Expect(wp.WebPodTemplateSpec().Spec.NodeSelector).To(BeNil())
+
+		options.DefaultNodeSelector = map[string]string{"workload": "wordpress", "tier": "web"}
+		Expect(wp.WebPodTemplateSpec().Spec.NodeSelector).To(Equal(options.DefaultNodeSelector))
+
+		wp.Spec.NodeSelector = map[string]string{"tier": "premium", "zone": "a"}
+		expected := map[string]string{"workload": "wordpress", "tier": "premium", "zone": "a"}
+		Expect(wp.WebPodTemplateSpec().Spec.NodeSelector).To(Equal(expected))
+		Expect(wp.JobPodTemplateSpec().Spec.NodeSelector).To(Equal(expected))
+		Expect(options.DefaultNodeSelector).To(HaveKeyWithValue("tier", "web"))
+	})
+
+	DescribeTable("should mount CephFS and Glusterfs media volumes",
+		func(media *wordpressv1alpha1.MediaVolumeSpec, source corev1.VolumeSource, chown bool) {
+			wp.Spec.MediaVolumeSpec = media
+			wp.SetDefaults()
+
+			spec := wp.WebPodTemplateSpec().Spec
+			Expect(spec.Volumes).To(ContainElement(corev1.Volume{Name: mediaVolumeName, VolumeSource: source}))
+
+			_, found := lookupEnvVar("STACK_MEDIA_BUCKET", spec.Containers[0].Env)
+			Expect(found).To(BeFalse())
+
+			Expect(spec.InitContainers[0].Name).To(Equal("prepare-volumes"))
+			mountsMedia := false
+			for _, m := range spec.InitContainers[0].VolumeMounts {
+				if m.Name == mediaVolumeName {
+					mountsMedia = true
+				}
+			}
+			Expect(mountsMedia).To(Equal(chown))
+		},
+		Entry("read-write CephFS",
+			&wordpressv1alpha1.MediaVolumeSpec{
+				CephFS: &corev1.CephFSVolumeSource{Monitors: []string{"10.0.0.1:6789"}},
+			},
+			corev1.VolumeSource{CephFS: &corev1.CephFSVolumeSource{Monitors: []string{"10.0.0.1:6789"}}},
+			true,
+		),
+		Entry("read-only CephFS",
+			&wordpressv1alpha1.MediaVolumeSpec{
+				CephFS: &corev1.CephFSVolumeSource{Monitors: []string{"10.0.0.1:6789"}, ReadOnly: true},
+			},
+			corev1.VolumeSource{CephFS: &corev1.CephFSVolumeSource{Monitors: []string{"10.0.0.1:6789"}, ReadOnly: true}},
+			false,
+		),
+		Entry("read-only mounted Glusterfs",
+			&wordpressv1alpha1.MediaVolumeSpec{
+				ReadOnly:  true,
+				Glusterfs: &corev1.GlusterfsVolumeSource{EndpointsName: "gluster", Path: "media"},
+			},
+			corev1.VolumeSource{Glusterfs: &corev1.GlusterfsVolumeSource{EndpointsName: "gluster", Path: "media"}},
+			false,
+		),
+	)
+
+	It("should mount the git reference repository when configured", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{
+				Repository:        "https://github.com/bitpoke/stack-example-wordpress.git",
+				ReferenceRepoPath: "/var/cache/git/stack-example-wordpress.git",
+			},
+		}
+		wp.SetDefaults()
+
+		spec := wp.WebPodTemplateSpec().Spec
+		Expect(spec.Volumes).To(ContainElement(wp.gitReferenceVolume()))
+		Expect(wp.gitReferenceVolume().HostPath.Path).To(Equal("/var/cache/git/stack-example-wordpress.git"))
+
+		git := spec.InitContainers[len(spec.InitContainers)-1]
+		Expect(git.Name).To(Equal("git"))
+		Expect(git.Env).To(ContainElement(corev1.EnvVar{Name: "GIT_CLONE_REFERENCE", Value: gitReferenceMountPath}))
+		Expect(git.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      gitReferenceVolume,
+			MountPath: gitReferenceMountPath,
+			ReadOnly:  true,
+		}))
+		for _, m := range spec.Containers[0].VolumeMounts {
+			Expect(m.Name).ToNot(Equal(gitReferenceVolume))
+		}
+	})
+
+	It("should run the wordpress and init containers as the www-data group", func() {
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		}
+		wp.SetDefaults()
+
+		spec := wp.WebPodTemplateSpec().Spec
+		Expect(*spec.Containers[0].SecurityContext.RunAsGroup).To(Equal(int64(33)))
+		Expect(spec.InitContainers[0].Args[2]).To(ContainSubstring("chown 33:33 /mnt/media"))
+
+		gid := int64(1000)
+		wp.Spec.RunAsGroup = &gid
+
+		spec = wp.WebPodTemplateSpec().Spec
+		Expect(*spec.Containers[0].SecurityContext.RunAsGroup).To(Equal(gid))
+		Expect(*wp.JobPodTemplateSpec().Spec.Containers[0].SecurityContext.RunAsGroup).To(Equal(gid))
+		Expect(*wp.JobPodTemplateSpec().Spec.SecurityContext.FSGroup).To(Equal(gid))
+		Expect(spec.InitContainers[0].Args[2]).To(ContainSubstring("chown 33:1000 /mnt/media"))
+	})
+
+	It("should retry the wordpress install", func() {
+		wp.Spec.WordpressBootstrapSpec = &wordpressv1alpha1.WordpressBootstrapSpec{}
+		wp.SetDefaults()
+
+		c := wp.WebPodTemplateSpec().Spec.InitContainers[0]
+		Expect(c.Name).To(Equal("install-wp"))
+		Expect(c.Command).To(Equal([]string{"/bin/sh", "-c", installWPScript, "wp-install"}))
+		Expect(c.Args[0]).To(Equal("$(WORDPRESS_BOOTSTRAP_TITLE)"))
+		Expect(c.Env).To(ContainElement(corev1.EnvVar{Name: "WORDPRESS_BOOTSTRAP_ATTEMPTS", Value: "3"}))
+		Expect(c.Env).To(ContainElement(corev1.EnvVar{Name: "WORDPRESS_BOOTSTRAP_RETRY_DELAY", Value: "10"}))
+
+		delay := int32(0)
+		wp.Spec.WordpressBootstrapSpec.Attempts = 5
+		wp.Spec.WordpressBootstrapSpec.RetryDelaySeconds = &delay
+
+		c = wp.WebPodTemplateSpec().Spec.InitContainers[0]
+		Expect(c.Env).To(ContainElement(corev1.EnvVar{Name: "WORDPRESS_BOOTSTRAP_ATTEMPTS", Value: "5"}))
+		Expect(c.Env).To(ContainElement(corev1.EnvVar{Name: "WORDPRESS_BOOTSTRAP_RETRY_DELAY", Value: "0"}))
+	})
+
+	It("should bound the git clone volume size when configured", func() {
+		medium := corev1.StorageMediumMemory
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{
+				Repository: "https://github.com/bitpoke/stack-example-wordpress.git",
+				EmptyDir:   &corev1.EmptyDirVolumeSource{Medium: medium},
+			},
+		}
+		wp.SetDefaults()
+		Expect(wp.codeVolume().EmptyDir.SizeLimit).To(BeNil())
+
+		sizeLimit := resource.MustParse("2Gi")
+		wp.Spec.CodeVolumeSpec.GitDir.SizeLimit = &sizeLimit
+
+		emptyDir := wp.codeVolume().EmptyDir
+		Expect(emptyDir.Medium).To(Equal(medium))
+		Expect(emptyDir.SizeLimit.Cmp(sizeLimit)).To(Equal(0))
+		Expect(wp.Spec.CodeVolumeSpec.GitDir.EmptyDir.SizeLimit).To(BeNil())
+	})
+
+	It("should bound the fallback code and media volumes size", func() {
+		Expect(wp.codeVolume().EmptyDir.SizeLimit.Cmp(defaultVolumeSizeLimit)).To(Equal(0))
+		Expect(wp.mediaVolume().EmptyDir.SizeLimit.Cmp(defaultVolumeSizeLimit)).To(Equal(0))
+
+		sizeLimit := resource.MustParse("20Gi")
+		wp.Spec.DefaultVolumeSizeLimit = &sizeLimit
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			S3VolumeSource: &wordpressv1alpha1.S3VolumeSource{Bucket: "media"},
+		}
+		Expect(wp.codeVolume().EmptyDir.SizeLimit.Cmp(sizeLimit)).To(Equal(0))
+		Expect(wp.mediaVolume().EmptyDir.SizeLimit.Cmp(sizeLimit)).To(Equal(0))
+
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		}
+		Expect(wp.mediaVolume().EmptyDir.SizeLimit).To(BeNil())
+	})
+
+	It("should back the log volume by memory when configured", func() {
+		logVolume := func() *corev1.EmptyDirVolumeSource {
+			for _, v := range wp.WebPodTemplateSpec().Spec.Volumes {
+				if v.Name == knativeVarLogVolume {
+					return v.EmptyDir
+				}
+			}
+			return nil
+		}
+
+		Expect(logVolume().Medium).To(Equal(corev1.StorageMediumDefault))
+
+		wp.Spec.LogVolumeMedium = corev1.StorageMediumMemory
+		Expect(logVolume().Medium).To(Equal(corev1.StorageMediumMemory))
+		Expect(logVolume().SizeLimit.Cmp(varLogSizeLimit)).To(Equal(0))
+	})
+
+	It("should only run the pre-stop scripts by default", func() {
+		spec := wp.WebPodTemplateSpec().Spec
+		Expect(spec.Containers[0].Lifecycle.PreStop.Exec.Command).To(Equal([]string{"/bin/sh", "-c", preStopScriptsCmd}))
+		Expect(*spec.TerminationGracePeriodSeconds).To(Equal(int64(30)))
+	})
+
+	It("should run the pre-stop scripts on a best effort basis when configured", func() {
+		wp.Spec.PreStopBestEffort = true
+		wp.Spec.DrainSeconds = 15
+
+		spec := wp.WebPodTemplateSpec().Spec
+		Expect(spec.Containers[0].Lifecycle.PreStop.Exec.Command).To(Equal([]string{
+			"/bin/sh", "-c", "sleep 15 ; " + preStopScriptsBestEffortCmd,
+		}))
+		Expect(preStopScriptsBestEffortCmd).To(ContainSubstring("timeout 20 run-parts -v"))
+		Expect(preStopScriptsBestEffortCmd).ToNot(ContainSubstring("--exit-on-error"))
+		Expect(*spec.TerminationGracePeriodSeconds).To(Equal(int64(45)))
+	})
+
+	It("should warm up the wordpress container after the post-start scripts", func() {
+		Expect(wp.WebPodTemplateSpec().Spec.Containers[0].Lifecycle.PostStart.Exec.Command).To(Equal([]string{
+			"/bin/sh", "-c", postStartScriptsCmd,
+		}))
+
+		wp.Spec.Warmup = &wordpressv1alpha1.WarmupSpec{
+			Paths: []wordpressv1alpha1.WarmupPath{"/", "/wp-login.php"},
+		}
+
+		curl := "curl -sS -o /dev/null --max-time 30 --retry 5 --retry-connrefused -H 'Host: test.com' 'http://127.0.0.1:8080%s' || true"
+		Expect(wp.WebPodTemplateSpec().Spec.Containers[0].Lifecycle.PostStart.Exec.Command).To(Equal([]string{
+			"/bin/sh", "-c",
+			"set -e ; " + postStartScriptsCmd + " ; " + fmt.Sprintf(curl, "/") + " ; " + fmt.Sprintf(curl, "/wp-login.php"),
+		}))
+
+		wp.Spec.ProbeScheme = corev1.URISchemeHTTPS
+		Expect(wp.WebPodTemplateSpec().Spec.Containers[0].Lifecycle.PostStart.Exec.Command[2]).To(ContainSubstring(
+			"curl -sS -k -o /dev/null --max-time 30 --retry 5 --retry-connrefused -H 'Host: test.com' 'https://127.0.0.1:8080/' || true"))
+	})
+
+	It("should warm up the wordpress container with a native hook when configured", func() {
+		wp.Spec.Warmup = &wordpressv1alpha1.WarmupSpec{
+			Paths: []wordpressv1alpha1.WarmupPath{"/"},
+		}
+		wp.Spec.PostStartWarmupPath = "/wp-login.php"
+		Expect(wp.ValidateWarmup()).To(MatchError(errWarmup))
+
+		wp.Spec.Warmup = nil
+		Expect(wp.ValidateWarmup()).To(Succeed())
+
+		hook := wp.WebPodTemplateSpec().Spec.Containers[0].Lifecycle.PostStart
+		Expect(hook.Exec).To(BeNil())
+		Expect(hook.HTTPGet).To(Equal(&corev1.HTTPGetAction{
+			Path:        "/wp-login.php",
+			Port:        intstr.FromInt(InternalHTTPPort),
+			HTTPHeaders: []corev1.HTTPHeader{{Name: "Host", Value: "test.com"}},
+		}))
+	})
+
+	It("should drain the web server before stopping", func() {
+		wp.Spec.DrainEndpoint = "/-/drain"
+		wp.Spec.DrainSeconds = 15
+
+		spec := wp.WebPodTemplateSpec().Spec
+		Expect(spec.Containers[0].Lifecycle.PreStop.Exec.Command).To(Equal([]string{
+			"/bin/sh", "-c",
+			"curl -fsS --max-time 5 'http://127.0.0.1:8080/-/drain' || true ; sleep 15 ; " + preStopScriptsCmd,
+		}))
+		Expect(*spec.TerminationGracePeriodSeconds).To(Equal(int64(45)))
+	})
+
+	It("should stop the sidecars before the wordpress container when configured", func() {
+		wp.Spec.Sidecars = []corev1.Container{
+			{Name: "nginx"},
+			{Name: "agent", Lifecycle: &corev1.Lifecycle{
+				PreStop: &corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"stop"}}},
+			}},
+		}
+		wp.Spec.DrainSeconds = 15
+		wp.Spec.ShutdownOrder = &wordpressv1alpha1.ShutdownOrderSpec{}
+		wp.SetDefaults()
+
+		// the sidecars stop after the 15s drain (at 20s), and wordpress after them (at 30s)
+		spec := wp.WebPodTemplateSpec().Spec
+		Expect(spec.Containers[0].Lifecycle.PreStop.Exec.Command).To(Equal([]string{
+			"/bin/sh", "-c", "sleep 15 ; sleep 15 ; " + preStopScriptsCmd,
+		}))
+		Expect(spec.Containers[1].Lifecycle.PreStop.Exec.Command).To(Equal([]string{"sleep", "20"}))
+		Expect(spec.Containers[2].Lifecycle.PreStop.Exec.Command).To(Equal([]string{"stop"}))
+		Expect(wp.Spec.Sidecars[0].Lifecycle).To(BeNil())
+		Expect(*spec.TerminationGracePeriodSeconds).To(Equal(int64(60)))
+	})
+
+	It("should set the common labels on pods and components", func() {
+		defer func(l map[string]string) { options.CommonLabels = l }(options.CommonLabels)
+
+		options.CommonLabels = map[string]string{
+			"team":                   "web",
+			"app.kubernetes.io/name": "custom",
+		}
+		wp.Spec.PodMetadata = &metav1.ObjectMeta{Labels: map[string]string{"team": "blog"}}
+
+		for _, l := range []map[string]string{
+			wp.WebPodTemplateSpec().Labels,
+			wp.JobPodTemplateSpec().Labels,
+		} {
+			Expect(l).To(HaveKeyWithValue("team", "blog"))
+			Expect(l).To(HaveKeyWithValue("app.kubernetes.io/name", "wordpress"))
+		}
+
+		l := wp.ComponentLabels(WordpressDeployment)
+		Expect(l).To(HaveKeyWithValue("team", "web"))
+		Expect(l).To(HaveKeyWithValue("app.kubernetes.io/name", "wordpress"))
+		Expect(wp.WebPodLabels()).ToNot(HaveKey("team"))
+	})
+
+	It("should leave image pull secrets to the managed service account", func() {
+		wp.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry"}}
+		Expect(wp.WebPodTemplateSpec().Spec.ImagePullSecrets).To(Equal(wp.Spec.ImagePullSecrets))
+
+		wp.Spec.ManageServiceAccount = true
+		Expect(wp.WebPodTemplateSpec().Spec.ImagePullSecrets).To(Equal(wp.Spec.ImagePullSecrets))
+
+		wp.Spec.ServiceAccountName = "wordpress"
+		Expect(wp.WebPodTemplateSpec().Spec.ImagePullSecrets).To(BeEmpty())
+		Expect(wp.JobPodTemplateSpec().Spec.ImagePullSecrets).To(BeEmpty())
+		Expect(wp.WebPodTemplateSpec().Spec.ServiceAccountName).To(Equal("wordpress"))
+	})
+
+	It("should mount media read-write in jobs when configured", func() {
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			ReadOnly: true,
+			HostPath: &corev1.HostPathVolumeSource{Path: "/media"},
+		}
+		wp.SetDefaults()
+
+		mediaMount := func(mounts []corev1.VolumeMount) (corev1.VolumeMount, bool) {
+			for _, m := range mounts {
+				if m.Name == mediaVolumeName {
+					return m, true
+				}
+			}
+
+			return corev1.VolumeMount{}, false
+		}
+
+		job := wp.JobPodTemplateSpec().Spec
+		m, _ := mediaMount(job.Containers[0].VolumeMounts)
+		Expect(m.ReadOnly).To(BeTrue())
+		_, found := mediaMount(job.InitContainers[0].VolumeMounts)
+		Expect(found).To(BeFalse())
+
+		wp.Spec.JobMediaReadWrite = true
+		job = wp.JobPodTemplateSpec().Spec
+		m, _ = mediaMount(job.Containers[0].VolumeMounts)
+		Expect(m.ReadOnly).To(BeFalse())
+		prepare := job.InitContainers[0]
+		Expect(prepare.Name).To(Equal("prepare-volumes"))
+		_, found = mediaMount(prepare.VolumeMounts)
+		Expect(found).To(BeTrue())
+
+		m, _ = mediaMount(wp.WebPodTemplateSpec().Spec.Containers[0].VolumeMounts)
+		Expect(m.ReadOnly).To(BeTrue())
+		Expect(wp.Spec.MediaVolumeSpec.ReadOnly).To(BeTrue())
+	})
+
+	It("should rely on the pod fsGroup for volume permissions when configured", func() {
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		}
+		wp.SetDefaults()
+		Expect(wp.Spec.VolumePermissionsMode).To(Equal(wordpressv1alpha1.VolumePermissionsChown))
+
+		spec := wp.WebPodTemplateSpec().Spec
+		prepare := spec.InitContainers[0]
+		Expect(prepare.Args[2]).To(HavePrefix("#!/bin/sh\ntest -d /mnt/code && chown 33:33 /mnt/code\n"))
+		Expect(prepare.SecurityContext).To(BeNil())
+		Expect(prepare.VolumeMounts).To(HaveLen(3))
+		Expect(spec.SecurityContext).To(BeNil())
+
+		wp.Spec.VolumePermissionsMode = wordpressv1alpha1.VolumePermissionsFSGroup
+		onRootMismatch := corev1.FSGroupChangeOnRootMismatch
+
+		for _, spec := range []corev1.PodSpec{wp.WebPodTemplateSpec().Spec, wp.JobPodTemplateSpec().Spec} {
+			prepare := spec.InitContainers[0]
+			Expect(prepare.Name).To(Equal("prepare-volumes"))
+			Expect(prepare.Args[2]).ToNot(ContainSubstring("chown"))
+			Expect(prepare.Args[2]).To(ContainSubstring("ln -sf"))
+			Expect(*prepare.SecurityContext.RunAsUser).To(Equal(wwwDataUserID))
+			Expect(prepare.VolumeMounts).To(HaveLen(2))
+
+			Expect(*spec.SecurityContext.FSGroup).To(Equal(wwwDataGroupID))
+			Expect(spec.SecurityContext.FSGroupChangePolicy).To(Equal(&onRootMismatch))
+		}
+	})
+
+	It("should expand the security profile into the containers security context", func() {
+		wp.Spec.CodeVolumeSpec = &wordpressv1alpha1.CodeVolumeSpec{
+			GitDir: &wordpressv1alpha1.GitVolumeSource{GitRef: "main"},
+		}
+		sc := wp.WebPodTemplateSpec().Spec.Containers[0].SecurityContext
+		Expect(sc.AllowPrivilegeEscalation).To(BeNil())
+		Expect(sc.SeccompProfile).To(BeNil())
+
+		wp.Spec.SecurityProfile = wordpressv1alpha1.SecurityProfileBaseline
+		sc = wp.WebPodTemplateSpec().Spec.Containers[0].SecurityContext
+		Expect(*sc.AllowPrivilegeEscalation).To(BeFalse())
+		Expect(sc.SeccompProfile.Type).To(Equal(corev1.SeccompProfileTypeRuntimeDefault))
+		Expect(sc.RunAsNonRoot).To(BeNil())
+		Expect(sc.Capabilities).To(BeNil())
+
+		wp.Spec.SecurityProfile = wordpressv1alpha1.SecurityProfileRestricted
+		Expect(wp.ValidateSecurityProfile()).To(MatchError(errSecurityProfile))
+
+		wp.Spec.VolumePermissionsMode = ""
+		wp.SetDefaults()
+		Expect(wp.Spec.VolumePermissionsMode).To(Equal(wordpressv1alpha1.VolumePermissionsFSGroup))
+		Expect(wp.ValidateSecurityProfile()).To(Succeed())
+		spec := wp.WebPodTemplateSpec().Spec
+		for _, c := range append(spec.InitContainers, spec.Containers...) {
+			Expect(*c.SecurityContext.RunAsNonRoot).To(BeTrue(), c.Name)
+			Expect(*c.SecurityContext.AllowPrivilegeEscalation).To(BeFalse(), c.Name)
+			Expect(c.SecurityContext.Capabilities.Drop).To(ConsistOf(corev1.Capability("ALL")), c.Name)
+			Expect(c.SecurityContext.SeccompProfile.Type).To(Equal(corev1.SeccompProfileTypeRuntimeDefault), c.Name)
+		}
+		Expect(spec.InitContainers[0].Args[2]).ToNot(ContainSubstring("chown"))
+		Expect(*spec.SecurityContext.FSGroup).To(Equal(wwwDataGroupID))
+
+		readOnly := true
+		allowPrivilegeEscalation := true
+		wp.Spec.SecurityContext = &corev1.SecurityContext{
+			ReadOnlyRootFilesystem:   &readOnly,
+			AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		}
+		sc = wp.WebPodTemplateSpec().Spec.Containers[0].SecurityContext
+		Expect(*sc.ReadOnlyRootFilesystem).To(BeTrue())
+		Expect(*sc.AllowPrivilegeEscalation).To(BeTrue())
+		Expect(*sc.RunAsNonRoot).To(BeTrue())
+		Expect(*sc.RunAsUser).To(Equal(wwwDataUserID))
+	})
+
+	It("should run jobs with their own service account", func() {
+		wp.Spec.ServiceAccountName = "wordpress"
+		Expect(wp.JobPodTemplateSpec().Spec.ServiceAccountName).To(Equal("wordpress"))
+
+		wp.Spec.JobServiceAccountName = "wordpress-jobs"
+		wp.Spec.ManageServiceAccount = true
+		wp.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry"}}
+
+		spec := wp.JobPodTemplateSpec().Spec
+		Expect(spec.ServiceAccountName).To(Equal("wordpress-jobs"))
+		Expect(spec.ImagePullSecrets).To(Equal(wp.Spec.ImagePullSecrets))
+		Expect(wp.WebPodTemplateSpec().Spec.ServiceAccountName).To(Equal("wordpress"))
+	})
+
+	It("should capture the job output when configured", func() {
+		wp.Spec.Sidecars = []corev1.Container{{Name: "uploader"}}
+		Expect(wp.JobPodTemplateSpec("wp", "search-replace").Spec.Containers[0].Args).To(Equal([]string{"wp", "search-replace"}))
+
+		wp.Spec.CaptureJobOutput = true
+		spec := wp.JobPodTemplateSpec("wp", "search-replace").Spec
+		Expect(spec.Containers[0].Args).To(Equal([]string{"/bin/bash", "-c", jobOutputCaptureCmd, "wp-cli", "wp", "search-replace"}))
+		Expect(spec.Volumes).To(ContainElement(corev1.Volume{
+			Name:         jobOutputVolume,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}))
+
+		mount := corev1.VolumeMount{Name: jobOutputVolume, MountPath: jobOutputMountPath}
+		Expect(spec.Containers[0].VolumeMounts).To(ContainElement(mount))
+		Expect(spec.Containers[1].VolumeMounts).To(ContainElement(mount))
+		Expect(wp.Spec.Sidecars[0].VolumeMounts).To(BeEmpty())
+
+		Expect(wp.WebPodTemplateSpec().Spec.Containers[0].VolumeMounts).ToNot(ContainElement(mount))
+	})
+
+	It("should set the job probe as the wp-cli container readiness probe", func() {
+		Expect(wp.JobPodTemplateSpec().Spec.Containers[0].ReadinessProbe).To(BeNil())
+		Expect(wp.JobPodTemplateSpec().Spec.Containers[0].LivenessProbe).To(BeNil())
+
+		wp.Spec.JobProbe = &corev1.Probe{
+			Handler: corev1.Handler{
+				Exec: &corev1.ExecAction{Command: []string{"test", "-f", "/tmp/progress"}},
+			},
+			PeriodSeconds: 30,
+		}
+
+		c := wp.JobPodTemplateSpec().Spec.Containers[0]
+		Expect(c.ReadinessProbe).To(Equal(wp.Spec.JobProbe))
+		Expect(c.LivenessProbe).To(BeNil())
+		Expect(wp.WebPodTemplateSpec().Spec.Containers[0].ReadinessProbe).ToNot(Equal(wp.Spec.JobProbe))
+	})
+
+	It("should generate idle debug pods", func() {
+		wp.Spec.CaptureJobOutput = true
+		wp.Spec.JobProbe = &corev1.Probe{}
+
+		spec := wp.DebugPodTemplateSpec().Spec
+		Expect(spec.Containers[0].Name).To(Equal(wp.Spec.CLIContainerName))
+		Expect(spec.Containers[0].Args).To(Equal([]string{"sleep", "infinity"}))
+		Expect(spec.Containers[0].Env).To(Equal(wp.JobPodTemplateSpec().Spec.Containers[0].Env))
+		Expect(spec.Containers[0].ReadinessProbe).To(BeNil())
+		Expect(wp.Spec.CaptureJobOutput).To(BeTrue())
+	})
+
+	It("should set the node failure toleration seconds when configured", func() {
+		Expect(wp.WebPodTemplateSpec().Spec.Tolerations).To(BeNil())
+
+		seconds := int64(30)
+		wp.Spec.DefaultTolerationSeconds = &seconds
+		wp.Spec.Tolerations = []corev1.Toleration{
+			{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+		}
+
+		tolerations := wp.WebPodTemplateSpec().Spec.Tolerations
+		Expect(tolerations).To(Equal([]corev1.Toleration{
+			wp.Spec.Tolerations[0],
+			{
+				Key:               corev1.TaintNodeNotReady,
+				Operator:          corev1.TolerationOpExists,
+				Effect:            corev1.TaintEffectNoExecute,
+				TolerationSeconds: &seconds,
+			},
+		}))
+		Expect(wp.JobPodTemplateSpec().Spec.Tolerations).To(Equal(tolerations))
+		Expect(wp.Spec.Tolerations).To(HaveLen(1))
+
+		wp.Spec.Tolerations = []corev1.Toleration{{Operator: corev1.TolerationOpExists}}
+		Expect(wp.WebPodTemplateSpec().Spec.Tolerations).To(Equal(wp.Spec.Tolerations))
+	})
+
+	It("should mount the shared session volume when configured", func() {
+		wp.Spec.SharedSessionVolume = &wordpressv1alpha1.SharedSessionVolumeSpec{}
+		wp.SetDefaults()
+		Expect(wp.ValidateSharedSessionVolume()).To(MatchError(errSharedSessionVolume))
+
+		wp.Spec.SharedSessionVolume.NFS = &corev1.NFSVolumeSource{Server: "nfs.example.com", Path: "/sessions"}
+		Expect(wp.ValidateSharedSessionVolume()).To(Succeed())
+
+		spec := wp.WebPodTemplateSpec().Spec
+		Expect(spec.Volumes).To(ContainElement(corev1.Volume{
+			Name:         "shared-session",
+			VolumeSource: corev1.VolumeSource{NFS: wp.Spec.SharedSessionVolume.NFS},
+		}))
+		Expect(spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      "shared-session",
+			MountPath: "/var/lib/php/sessions",
+		}))
+		e, _ := lookupEnvVar("PHP_SESSION_SAVE_PATH", spec.Containers[0].Env)
+		Expect(e.Value).To(Equal("/var/lib/php/sessions"))
+
+		wp.Spec.SharedSessionVolume.MountPath = "/etc/podinfo/sessions"
+		Expect(wp.ValidateSharedSessionVolume()).To(MatchError(errSharedSessionVolume))
+	})
+
+	It("should reject conflicting media sources", func() {
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		}
+		Expect(wp.ValidateMediaVolume()).To(Succeed())
+
+		wp.Spec.MediaVolumeSpec.S3VolumeSource = &wordpressv1alpha1.S3VolumeSource{Bucket: "media"}
+		Expect(wp.ValidateMediaVolume()).To(MatchError(errConflictingMedia))
+		Expect(wp.ValidateMediaVolume().Error()).To(ContainSubstring(".spec.media.s3 and .spec.media.emptyDir"))
+
+		wp.Spec.MediaVolumeSpec.EmptyDir = nil
+		Expect(wp.ValidateMediaVolume()).To(Succeed())
+
+		wp.Spec.MediaVolumeSpec.GCSVolumeSource = &wordpressv1alpha1.GCSVolumeSource{Bucket: "media"}
+		Expect(wp.ValidateMediaVolume()).To(MatchError(errConflictingMedia))
+	})
+
+	It("should project a media service account token when configured", func() {
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			S3VolumeSource: &wordpressv1alpha1.S3VolumeSource{Bucket: "media"},
+			TokenAudience:  "sts.amazonaws.com",
+		}
+		Expect(wp.ValidateMediaVolume()).To(MatchError(errInvalidMediaToken))
+		Expect(wp.WebPodTemplateSpec().Spec.Volumes).ToNot(ContainElement(wp.mediaTokenVolume()))
+
+		wp.Spec.MediaVolumeSpec.RoleARN = "arn:aws:iam::123456789012:role/media"
+		Expect(wp.ValidateMediaVolume()).To(Succeed())
+
+		spec := wp.WebPodTemplateSpec().Spec
+		Expect(spec.Volumes).To(ContainElement(wp.mediaTokenVolume()))
+		Expect(wp.mediaTokenVolume().Projected.Sources[0].ServiceAccountToken.Audience).To(Equal("sts.amazonaws.com"))
+		Expect(spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      "media-token",
+			MountPath: "/var/run/secrets/presslabs.org/media",
+			ReadOnly:  true,
+		}))
+
+		e, _ := lookupEnvVar("AWS_WEB_IDENTITY_TOKEN_FILE", spec.Containers[0].Env)
+		Expect(e.Value).To(Equal("/var/run/secrets/presslabs.org/media/token"))
+		e, _ = lookupEnvVar("AWS_ROLE_ARN", spec.Containers[0].Env)
+		Expect(e.Value).To(Equal(wp.Spec.MediaVolumeSpec.RoleARN))
+	})
+
+	It("should prefix the S3 media files by site when configured", func() {
+		wp.Spec.MediaVolumeSpec = &wordpressv1alpha1.MediaVolumeSpec{
+			S3VolumeSource: &wordpressv1alpha1.S3VolumeSource{Bucket: "media"},
+		}
+		bucket := func() string {
+			e, _ := lookupEnvVar("STACK_MEDIA_BUCKET", wp.mediaEnv())
+			return e.Value
+		}
+		Expect(bucket()).To(Equal("s3://media"))
+
+		wp.Spec.MediaVolumeSpec.S3VolumeSource.SitePathPrefix = true
+		Expect(bucket()).To(Equal(fmt.Sprintf("s3://media/default/%s", wp.Name)))
+		Expect(wp.ValidateMediaVolume()).To(Succeed())
+
+		wp.Spec.MediaVolumeSpec.S3VolumeSource.PathPrefix = "shared/../other"
+		Expect(wp.ValidateMediaVolume()).To(MatchError(errInvalidMediaPrefix))
+
+		wp.Spec.MediaVolumeSpec.S3VolumeSource.PathPrefix = "sites/blog"
+		Expect(bucket()).To(Equal("s3://media/sites/blog"))
+		Expect(wp.ValidateMediaVolume()).To(Succeed())
+	})
+})
+
+// expandEnv resolves $(VAR_NAME) references to previously defined vars, like the kubelet does.
+func expandEnv(env []corev1.EnvVar) map[string]string {
+	out := map[string]string{}
+
+	for _, e := range env {
+		value := e.Value
+		for name, v := range out {
+			value = strings.ReplaceAll(value, fmt.Sprintf("$(%s)", name), v)
+		}
+
+		out[e.Name] = value
+	}
+
+	return out
+}
+
+// initGitRepo creates a git repository in dir, with one commit on the given
+// branch, and returns its path.
+func initGitRepo(dir, branch string) string {
+	repo := filepath.Join(dir, "repo")
+
+	for _, args := range [][]string{
+		{"init", "-q", repo},
+		{"-C", repo, "checkout", "-q", "-b", branch},
+		{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		Expect(exec.Command("git", args...).Run()).To(Succeed())
+	}
+
+	return repo
+}
+
+// runGitClone runs the git clone container command locally, cloning into
+// src, and returns its output.
+func runGitClone(c corev1.Container, src string) (string, error) {
+	cmd := exec.Command(c.Args[0], c.Args[1:]...)
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH")}
+
+	for _, e := range c.Env {
+		if e.Name == "SRC_DIR" {
+			e.Value = src
+		}
+
+		cmd.Env = append(cmd.Env, e.Name+"="+e.Value)
+	}
+
+	out, err := cmd.CombinedOutput()
+
+	return string(out), err
+}
 
 // nolint: unparam
 func lookupEnvVar(name string, env []corev1.EnvVar) (corev1.EnvVar, bool) {
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 Pressinfra SRL.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wordpress
+
+import (
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func (wp *Wordpress) vaultAgentContainer() corev1.Container {
+	return corev1.Container{
+		Name:  "vault-agent",
+		Image: wp.Spec.VaultAgent.Image,
+		Args: []string{
+			"agent",
+			"-config=" + path.Join(vaultAgentConfigMountPath, "agent.hcl"),
+			"-exit-after-auth",
+		},
+		Env:                      wp.Spec.VaultAgent.Env,
+		SecurityContext:          wp.securityContext(),
+		TerminationMessagePolicy: wp.Spec.TerminationMessagePolicy,
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      vaultAgentConfigVolume,
+				MountPath: vaultAgentConfigMountPath,
+				ReadOnly:  true,
+			},
+			{
+				Name:      vaultSecretsVolume,
+				MountPath: wp.Spec.VaultAgent.MountPath,
+			},
+		},
+	}
+}
+
+func (wp *Wordpress) vaultVolumes() []corev1.Volume {
+	return []corev1.Volume{
+		{
+			Name: vaultAgentConfigVolume,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: wp.Spec.VaultAgent.ConfigMapName},
+				},
+			},
+		},
+		{
+			Name: vaultSecretsVolume,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+			},
+		},
+	}
+}
+
+func (wp *Wordpress) vaultSecretsVolumeMount() corev1.VolumeMount {
+	return corev1.VolumeMount{
+		Name:      vaultSecretsVolume,
+		MountPath: wp.Spec.VaultAgent.MountPath,
+		ReadOnly:  true,
+	}
+}
+
+func (wp *Wordpress) vaultEnv() []corev1.EnvVar {
+	if wp.Spec.VaultAgent == nil {
+		return nil
+	}
+
+	return []corev1.EnvVar{
+		{
+			Name:  "VAULT_SECRETS_FILE",
+			Value: path.Join(wp.Spec.VaultAgent.MountPath, wp.Spec.VaultAgent.SecretsFile),
+		},
+	}
+}
@@ -19,11 +19,17 @@ package wordpress
 import (
 	"fmt"
 	"path"
+	"strconv"
+	"time"
 
 	"github.com/cooleo/slugify"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	wordpressv1alpha1 "github.com/bitpoke/wordpress-operator/pkg/apis/wordpress/v1alpha1"
+	"github.com/bitpoke/wordpress-operator/pkg/cmd/options"
 )
 
 // Wordpress embeds wordpressv1alpha1.Wordpress and adds utility functions.
@@ -42,6 +48,10 @@ var (
 	WordpressSecret = component{name: "web", objNameFmt: "%s-wp"}
 	// WordpressDeployment component.
 	WordpressDeployment = component{name: "web", objNameFmt: "%s"}
+	// WordpressStatefulSet component.
+	WordpressStatefulSet = component{name: "web", objNameFmt: "%s"}
+	// WordpressCanaryDeployment component.
+	WordpressCanaryDeployment = component{name: "web", objNameFmt: "%s-canary"}
 	// WordpressCron component.
 	WordpressCron = component{name: "cron", objNameFmt: "%s-wp-cron"}
 	// WordpressDBUpgrade component.
@@ -52,6 +62,8 @@ var (
 	WordpressIngress = component{name: "web", objNameFmt: "%s"}
 	// WordpressCodePVC component.
 	WordpressCodePVC = component{name: "code", objNameFmt: "%s-code"}
+	// WordpressVPA component.
+	WordpressVPA = component{name: "web", objNameFmt: "%s"}
 	// WordpressMediaPVC component.
 	WordpressMediaPVC = component{name: "media", objNameFmt: "%s-media"}
 )
@@ -83,8 +95,9 @@ func (wp *Wordpress) Labels() labels.Set {
 }
 
 // ComponentLabels returns labels for a label set for a wordpressv1alpha1.Wordpress component.
+// The operator common labels are included, the component labels taking precedence.
 func (wp *Wordpress) ComponentLabels(component component) labels.Set {
-	l := wp.Labels()
+	l := labels.Merge(options.CommonLabels, wp.Labels())
 	l["app.kubernetes.io/component"] = component.name
 
 	if component == WordpressDBUpgrade {
@@ -114,6 +127,78 @@ func (wp *Wordpress) ImageVersion() string {
 	return slugify.Slugify(wp.Spec.Image)
 }
 
+const (
+	trackLabel  = "wordpress.presslabs.org/track"
+	canaryTrack = "canary"
+)
+
+// selectorLabels returns the stable subset of the default labels, which
+// selectors are built on.
+func (wp *Wordpress) selectorLabels() labels.Set {
+	return labels.Set{
+		"app.kubernetes.io/name":     "wordpress",
+		"app.kubernetes.io/instance": wp.ObjectMeta.Name,
+	}
+}
+
+// WebPodSelectorLabels returns the labels the web pods are selected by. Web
+// pods carry them, along with the rest of WebPodLabels. Canary pods carry
+// them too, so that the service sends them their share of the traffic.
+func (wp *Wordpress) WebPodSelectorLabels() labels.Set {
+	l := wp.selectorLabels()
+	l["app.kubernetes.io/component"] = "web"
+
+	return l
+}
+
+// WebPodSelector returns the selector of the web workload, which leaves out
+// the canary pods, for them not to be counted as the workload's own pods (eg.
+// by autoscalers).
+func (wp *Wordpress) WebPodSelector() *metav1.LabelSelector {
+	s := metav1.SetAsLabelSelector(wp.WebPodSelectorLabels())
+	s.MatchExpressions = []metav1.LabelSelectorRequirement{
+		{
+			Key:      trackLabel,
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   []string{canaryTrack},
+		},
+	}
+
+	return s
+}
+
+// SelectorChanged reports whether a workload selecting on the old selector no
+// longer selects pods with the new generated labels. Workload selectors are
+// immutable, so such workloads have to be recreated.
+func SelectorChanged(oldSelector *metav1.LabelSelector, newLabels labels.Set) bool {
+	s, err := metav1.LabelSelectorAsSelector(oldSelector)
+	if err != nil || s.Empty() {
+		return true
+	}
+
+	return !s.Matches(newLabels)
+}
+
+// WebSelectorChanged reports whether a web workload selecting on the old
+// selector has to be recreated: when it no longer selects the pods with the
+// given labels, or when it selects the canary pods too, as the selectors of
+// workloads created before the canary pods were left out do.
+func (wp *Wordpress) WebSelectorChanged(oldSelector *metav1.LabelSelector, newLabels labels.Set) bool {
+	if SelectorChanged(oldSelector, newLabels) {
+		return true
+	}
+
+	return wp.Spec.Canary != nil && !SelectorChanged(oldSelector, wp.CanaryPodLabels())
+}
+
+// CanaryPodSelectorLabels returns the labels the canary web pods are selected by.
+func (wp *Wordpress) CanaryPodSelectorLabels() labels.Set {
+	l := wp.WebPodSelectorLabels()
+	l[trackLabel] = canaryTrack
+
+	return l
+}
+
 // WebPodLabels return labels to apply to web pods.
 func (wp *Wordpress) WebPodLabels() labels.Set {
 	l := wp.Labels()
@@ -122,6 +207,14 @@ func (wp *Wordpress) WebPodLabels() labels.Set {
 	return l
 }
 
+// CanaryPodLabels return labels to apply to canary web pods.
+func (wp *Wordpress) CanaryPodLabels() labels.Set {
+	l := wp.WebPodLabels()
+	l[trackLabel] = canaryTrack
+
+	return l
+}
+
 // JobPodLabels return labels to apply to cli job pods.
 func (wp *Wordpress) JobPodLabels() labels.Set {
 	l := wp.Labels()
@@ -130,16 +223,109 @@ func (wp *Wordpress) JobPodLabels() labels.Set {
 	return l
 }
 
+// ManagesServiceAccount returns true if the image pull secrets are set on the
+// site's service account instead of on its pods.
+func (wp *Wordpress) ManagesServiceAccount() bool {
+	return wp.Spec.ManageServiceAccount && len(wp.Spec.ServiceAccountName) > 0
+}
+
+// RolloutSecretNames returns the names of the secrets whose changes roll out
+// the web pods. It is empty unless RestartOnSecretChange is set.
+func (wp *Wordpress) RolloutSecretNames() []string {
+	if !wp.Spec.RestartOnSecretChange {
+		return nil
+	}
+
+	var names []string
+
+	for _, src := range wp.Spec.EnvFrom {
+		if src.SecretRef != nil {
+			names = append(names, src.SecretRef.Name)
+		}
+	}
+
+	return names
+}
+
+// ValidateRoutes checks that .spec.routes annotations are valid object annotations
+// and that at most one route is primary.
+func (wp *Wordpress) ValidateRoutes() error {
+	var errs field.ErrorList
+
+	primary := false
+
+	for i := range wp.Spec.Routes {
+		fldPath := field.NewPath("spec", "routes").Index(i)
+		errs = append(errs, apivalidation.ValidateAnnotations(wp.Spec.Routes[i].Annotations, fldPath.Child("annotations"))...)
+
+		if wp.Spec.Routes[i].Primary && primary {
+			errs = append(errs, field.Invalid(fldPath.Child("primary"), true, "only one route can be primary"))
+		}
+
+		primary = primary || wp.Spec.Routes[i].Primary
+	}
+
+	return errs.ToAggregate()
+}
+
+// ValidateReplicas checks that the .spec.replicasFromAnnotation annotation,
+// if present, holds a positive integer.
+func (wp *Wordpress) ValidateReplicas() error {
+	_, err := wp.annotationReplicas()
+
+	return err
+}
+
+// annotationReplicas returns the replicas count set by the
+// .spec.replicasFromAnnotation annotation, or nil if there is none.
+func (wp *Wordpress) annotationReplicas() (*int32, error) {
+	if len(wp.Spec.ReplicasFromAnnotation) == 0 {
+		return nil, nil
+	}
+
+	value, ok := wp.ObjectMeta.Annotations[wp.Spec.ReplicasFromAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || replicas < 1 {
+		fldPath := field.NewPath("metadata", "annotations").Key(wp.Spec.ReplicasFromAnnotation)
+
+		return nil, field.Invalid(fldPath, value, "must be a positive integer")
+	}
+
+	r := int32(replicas)
+
+	return &r, nil
+}
+
 // MainDomain returns the site main domain or a local domain <cluster-name>.<namespace>.svc.cluster.local.
 func (wp *Wordpress) MainDomain() string {
-	if len(wp.Spec.Routes) > 0 {
-		return wp.Spec.Routes[0].Domain
+	if r := wp.primaryRoute(); r != nil {
+		return r.Domain
 	}
 
 	// return the local cluster name that points to wordpress service
 	return fmt.Sprintf("%s.%s.svc", wp.ComponentName(WordpressService), wp.Namespace)
 }
 
+// primaryRoute returns the route marked as primary, defaulting to the first
+// one, or nil if there are no routes.
+func (wp *Wordpress) primaryRoute() *wordpressv1alpha1.RouteSpec {
+	if len(wp.Spec.Routes) == 0 {
+		return nil
+	}
+
+	for i := range wp.Spec.Routes {
+		if wp.Spec.Routes[i].Primary {
+			return &wp.Spec.Routes[i]
+		}
+	}
+
+	return &wp.Spec.Routes[0]
+}
+
 // HomeURL returns the WP_HOMEURL (e.g. http://example.com/)
 func (wp *Wordpress) HomeURL(subPaths ...string) string {
 	scheme := "http"
@@ -148,8 +334,8 @@ func (wp *Wordpress) HomeURL(subPaths ...string) string {
 	}
 
 	paths := []string{"/"}
-	if len(wp.Spec.Routes) > 0 {
-		paths = append(paths, wp.Spec.Routes[0].Path)
+	if r := wp.primaryRoute(); r != nil {
+		paths = append(paths, r.Path)
 	}
 
 	paths = append(paths, subPaths...)
@@ -169,3 +355,42 @@ func (wp *Wordpress) SiteURL(subPaths ...string) string {
 
 	return wp.HomeURL(p...)
 }
+
+// InMaintenanceWindow returns whether the given time falls within the site's
+// daily maintenance window, along with the time left until the window ends
+// or, outside of it, until it starts.
+func (wp *Wordpress) InMaintenanceWindow(now time.Time) (bool, time.Duration) {
+	if wp.Spec.MaintenanceWindow == nil {
+		return false, 0
+	}
+
+	start, err := time.Parse("15:04", wp.Spec.MaintenanceWindow.Start)
+	if err != nil {
+		return false, 0
+	}
+
+	end, err := time.Parse("15:04", wp.Spec.MaintenanceWindow.End)
+	if err != nil {
+		return false, 0
+	}
+
+	now = now.UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	startOfDay := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	sinceStart := dayOffset(now.Sub(midnight) - start.Sub(startOfDay))
+	length := dayOffset(end.Sub(start))
+
+	if sinceStart < length {
+		return true, length - sinceStart
+	}
+
+	return false, 24*time.Hour - sinceStart
+}
+
+// dayOffset wraps the duration within a day.
+func dayOffset(d time.Duration) time.Duration {
+	day := 24 * time.Hour
+
+	return (d%day + day) % day
+}